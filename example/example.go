@@ -13,20 +13,23 @@ func example() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client := mongodb.New(ctx, "mongodb://localhost:27017", "example", nil)
-
-	defer func() {
-		if err := client.Disconnect(ctx); err != nil {
-			panic(err)
-		}
-	}()
-
 	logger, err := zap.NewProductionConfig().Build()
 	if err != nil {
 		log.Fatalf("failed to create logger: %v", err)
 	}
 
+	client, err := mongodb.New(ctx, "mongodb://localhost:27017", "example", logger)
+	if err != nil {
+		log.Fatalf("failed to create mongo client: %v", err)
+	}
+
 	storage := mongostorage.New(client.Database("example-database"))
 	retryingStorage := mongostorage.NewRetry(storage, logger)
+	defer func() {
+		if err := retryingStorage.Close(ctx); err != nil {
+			panic(err)
+		}
+	}()
+
 	retryingStorage.GetDatabaseName()
 }
@@ -2,18 +2,65 @@ package mongodb
 
 import (
 	"context"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/zap"
 )
 
-// New creates new instance of the MongoDB client
-func New(ctx context.Context, dsn string, name string, logger *zap.Logger) *mongo.Client {
+// New creates new instance of the MongoDB client. Operations are bounded by DefaultTimeout unless configured otherwise.
+// A nil logger discards the client logs.
+func New(ctx context.Context, dsn string, name string, logger *zap.Logger, opts ...Option) (*mongo.Client, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	cfg := newConfig(opts)
+
 	clientOptions := options.Client().ApplyURI(dsn).SetAppName(name)
+	if cfg.logPoolEvents {
+		clientOptions.SetPoolMonitor(poolEventLogger(logger))
+	}
+	if cfg.int64Integers {
+		clientOptions.SetRegistry(int64IntegersRegistry())
+	}
 
-	client, err := mongo.Connect(ctx, clientOptions)
+	timeout := cfg.timeout
+	if timeout == nil && clientOptions.Timeout == nil {
+		defaultTimeout := DefaultTimeout
+		timeout = &defaultTimeout
+	}
+	if timeout != nil && *timeout > 0 {
+		clientOptions.SetTimeout(*timeout)
+	}
+
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{clientOptions}, cfg.clientOptions...)...)
 	if err != nil {
+		return nil, err
+	}
+
+	if cfg.verify {
+		if err = client.Ping(ctx, readpref.Primary()); err != nil {
+			_ = client.Disconnect(ctx)
+
+			return nil, fmt.Errorf("failed to verify mongo connection: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// MustNew works like New, but logs the error at fatal level, ending the process, when the client can't be created.
+// With a nil logger it panics with the error instead.
+func MustNew(ctx context.Context, dsn string, name string, logger *zap.Logger, opts ...Option) *mongo.Client {
+	client, err := New(ctx, dsn, name, logger, opts...)
+	if err != nil {
+		if logger == nil {
+			panic(fmt.Errorf("failed to initiate a mongo client: %w", err))
+		}
+
 		logger.Fatal("failed to initiate a mongo client", zap.Error(err))
 	}
 
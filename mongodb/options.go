@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout bounds every operation of clients created by New, unless configured otherwise with WithTimeout
+// or the timeoutMS parameter of the connection string.
+const DefaultTimeout = 30 * time.Second
+
+// Option configures the client created by New
+type Option func(*config)
+
+type config struct {
+	logPoolEvents bool
+	int64Integers bool
+	timeout       *time.Duration
+	verify        bool
+	clientOptions []*options.ClientOptions
+}
+
+// WithPoolEventLogging logs connection pool events, i.e. connections being created or closed and the pool being
+// cleared, at debug level. Useful to diagnose connection churn during failovers.
+func WithPoolEventLogging() Option {
+	return func(cfg *config) {
+		cfg.logPoolEvents = true
+	}
+}
+
+// WithInt64Integers decodes every BSON integer into int64 when the destination is an interface{}, e.g. the values of a
+// bson.M, instead of int32 or int64 depending on how the number was stored. Typed destinations are not affected.
+func WithInt64Integers() Option {
+	return func(cfg *config) {
+		cfg.int64Integers = true
+	}
+}
+
+// WithTimeout bounds every operation of the client to timeout, unless its context has an earlier deadline.
+// A zero timeout disables the default and lets operations run as long as their context allows.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timeout = &timeout
+	}
+}
+
+// WithClientOptions applies driver options on top of those set by New, e.g. options.Client().SetMaxPoolSize(50).
+func WithClientOptions(clientOptions ...*options.ClientOptions) Option {
+	return func(cfg *config) {
+		cfg.clientOptions = append(cfg.clientOptions, clientOptions...)
+	}
+}
+
+// WithVerifyConnection makes New ping the primary after connecting, so an unreachable server is reported when
+// the client is created rather than by its first operation. The ping is bounded by the context passed to New.
+func WithVerifyConnection() Option {
+	return func(cfg *config) {
+		cfg.verify = true
+	}
+}
+
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// poolEventLogger returns a pool monitor logging connection lifecycle events to logger
+func poolEventLogger(logger *zap.Logger) *event.PoolMonitor {
+	return &event.PoolMonitor{Event: func(evt *event.PoolEvent) {
+		switch evt.Type {
+		case event.ConnectionCreated, event.ConnectionClosed, event.PoolCleared:
+			logger.Debug("mongodb connection pool event",
+				zap.String("type", evt.Type),
+				zap.String("address", evt.Address),
+				zap.Uint64("connectionID", evt.ConnectionID),
+				zap.String("reason", evt.Reason),
+				zap.Error(evt.Error))
+		}
+	}}
+}
+
+// int64IntegersRegistry returns the default registry with integers decoded into interface{} as int64
+func int64IntegersRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeMapEntry(bsontype.Int32, reflect.TypeOf(int64(0)))
+
+	return registry
+}
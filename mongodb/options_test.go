@@ -0,0 +1,33 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWithInt64IntegersDecodesIntegersAsInt64(t *testing.T) {
+	cfg := newConfig([]Option{WithInt64Integers()})
+	require.True(t, cfg.int64Integers)
+
+	raw, err := bson.Marshal(bson.D{
+		{Key: "small", Value: int32(1)},
+		{Key: "large", Value: int64(1) << 40},
+		{Key: "nested", Value: bson.D{{Key: "value", Value: int32(2)}}},
+		{Key: "list", Value: bson.A{int32(3)}},
+	})
+	require.NoError(t, err)
+
+	var doc bson.M
+	require.NoError(t, bson.UnmarshalWithRegistry(int64IntegersRegistry(), raw, &doc))
+	assert.Equal(t, int64(1), doc["small"])
+	assert.Equal(t, int64(1)<<40, doc["large"])
+	assert.Equal(t, bson.M{"value": int64(2)}, doc["nested"])
+	assert.Equal(t, bson.A{int64(3)}, doc["list"])
+
+	var value interface{}
+	require.NoError(t, bson.UnmarshalWithRegistry(int64IntegersRegistry(), raw, &value))
+	assert.Equal(t, int64(1), value.(bson.D)[0].Value)
+}
@@ -3,9 +3,12 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,9 +22,14 @@ type TestDBSuite struct {
 	suite.Suite
 	DSN    string
 	DBName string
+	// Timeout bounds every database call made by the suite helpers. Defaults to DefaultTestTimeout.
+	Timeout time.Duration
 	TestDB
 }
 
+// DefaultTestTimeout is the default time a single suite helper may spend talking to the database.
+const DefaultTestTimeout = 30 * time.Second
+
 // TestDB defines db client and data access layers.
 type TestDB struct {
 	MongoClient *mongo.Client
@@ -43,8 +51,9 @@ func NewTestDBSuite(database string) TestDBSuite {
 	mongoDSN := GetMongoDSN()
 
 	return TestDBSuite{
-		DSN:    mongoDSN,
-		DBName: database,
+		DSN:     mongoDSN,
+		DBName:  database,
+		Timeout: DefaultTestTimeout,
 	}
 }
 
@@ -60,14 +69,36 @@ func (t *TestDBSuite) SetupSuite() {
 
 // TruncateCollection will remove all documents from a given collection
 func (t *TestDBSuite) TruncateCollection(collection string) {
-	t.TestDB.TruncateCollection(collection)
+	// nolint: errcheck // reason: here we don't care as it's part of the tests
+	_ = t.withTimeout("truncating collection "+collection, func(ctx context.Context) error {
+		_, err := t.Database.Truncate(ctx, collection)
+		return err
+	})
 }
 
 // DropCollection will drop the collection
 func (t *TestDBSuite) DropCollection(collection string) {
-	db := t.MongoClient.Database(t.DBName)
-	t.NoError(db.Collection(collection).Drop(context.Background()))
-	return
+	t.NoError(t.withTimeout("dropping collection "+collection, func(ctx context.Context) error {
+		return t.Database.DropCollection(ctx, collection)
+	}))
+}
+
+// withTimeout runs fn with a context bounded by the suite timeout and fails the test straight away when it expires.
+func (t *TestDBSuite) withTimeout(action string, fn func(ctx context.Context) error) error {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := fn(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.FailNow(fmt.Sprintf("%s timed out after %s: %v", action, timeout, err))
+	}
+
+	return err
 }
 
 func NewTestDatabase(dsn, dbName string) (TestDB, error) {
@@ -85,7 +116,7 @@ func NewTestDatabase(dsn, dbName string) (TestDB, error) {
 // TruncateCollection will remove all documents from a given collection
 func (t *TestDB) TruncateCollection(collection string) {
 	// nolint: errcheck // reason: here we don't care as it's part of the tests
-	_, _ = t.Database.DeleteMany(context.Background(), collection, bson.M{})
+	_, _ = t.Database.Truncate(context.Background(), collection)
 }
 
 func loadSchema(filename string) (bson.M, error) {
@@ -94,6 +125,21 @@ func loadSchema(filename string) (bson.M, error) {
 		return nil, err
 	}
 
+	return parseSchema(fileBytes)
+}
+
+// loadSchemaFS reads the JSON schema at filename from fsys.
+func loadSchemaFS(fsys fs.FS, filename string) (bson.M, error) {
+	fileBytes, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSchema(fileBytes)
+}
+
+// parseSchema decodes a JSON schema.
+func parseSchema(fileBytes []byte) (bson.M, error) {
 	var schema bson.M
 	if err := json.Unmarshal(fileBytes, &schema); err != nil {
 		return nil, err
@@ -101,19 +147,105 @@ func loadSchema(filename string) (bson.M, error) {
 	return schema, nil
 }
 
-func (t *TestDBSuite) EnforceCollectionSchema(collectionName string, schemaPath string) error {
-	db := t.MongoClient.Database(t.DBName)
+// SchemaOption configures how EnforceCollectionSchema validates documents.
+type SchemaOption func(*schemaConfig)
+
+type schemaConfig struct {
+	validationLevel  string
+	validationAction string
+}
+
+// WithValidationLevel sets which documents the schema applies to: "strict" for every insert and update, the
+// default, "moderate" for updates of documents that are already valid only, or "off".
+func WithValidationLevel(level string) SchemaOption {
+	return func(cfg *schemaConfig) {
+		cfg.validationLevel = level
+	}
+}
+
+// WithValidationAction sets what happens to invalid documents: "error" rejects them, the default, "warn" only logs.
+func WithValidationAction(action string) SchemaOption {
+	return func(cfg *schemaConfig) {
+		cfg.validationAction = action
+	}
+}
+
+// namespaceExistsCode is the server error code of creating a collection that already exists.
+const namespaceExistsCode = 48
+
+// EnforceCollectionSchema creates the collection with the JSON schema at schemaPath as validator. When the collection
+// already exists, its validator is replaced instead, so it can be called by every test setup.
+func (t *TestDBSuite) EnforceCollectionSchema(collectionName string, schemaPath string, opts ...SchemaOption) error {
 	schema, err := loadSchema(schemaPath)
 	if err != nil {
 		return fmt.Errorf("failed to load schema: %s", err)
 	}
 
-	// Create new collection with schema validation
-	opts := options.CreateCollection().SetValidator(schema)
-	err = db.CreateCollection(context.Background(), collectionName, opts)
+	return t.enforceSchema(collectionName, schema, opts)
+}
+
+// EnforceCollectionSchemaFromFS works like EnforceCollectionSchema, reading the schema at schemaPath from fsys, e.g.
+// an embed.FS holding the schemas compiled into the test binary.
+func (t *TestDBSuite) EnforceCollectionSchemaFromFS(fsys fs.FS, collectionName, schemaPath string, opts ...SchemaOption) error {
+	schema, err := loadSchemaFS(fsys, schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %s", err)
+	}
+
+	return t.enforceSchema(collectionName, schema, opts)
+}
+
+// enforceSchema creates the collection with schema as validator, or updates the validator of an existing one.
+func (t *TestDBSuite) enforceSchema(collectionName string, schema bson.M, opts []SchemaOption) error {
+	var cfg schemaConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	db := t.MongoClient.Database(t.DBName)
+	err := t.withTimeout("creating collection "+collectionName, func(ctx context.Context) error {
+		exists, err := mongostorage.New(db).CollectionExists(ctx, collectionName)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			// Create new collection with schema validation
+			createOptions := options.CreateCollection().SetValidator(schema)
+			if cfg.validationLevel != "" {
+				createOptions.SetValidationLevel(cfg.validationLevel)
+			}
+			if cfg.validationAction != "" {
+				createOptions.SetValidationAction(cfg.validationAction)
+			}
+
+			err = db.CreateCollection(ctx, collectionName, createOptions)
+			// created concurrently since the check
+			if !isNamespaceExists(err) {
+				return err
+			}
+		}
+
+		command := bson.D{{Key: "collMod", Value: collectionName}, {Key: "validator", Value: schema}}
+		if cfg.validationLevel != "" {
+			command = append(command, bson.E{Key: "validationLevel", Value: cfg.validationLevel})
+		}
+		if cfg.validationAction != "" {
+			command = append(command, bson.E{Key: "validationAction", Value: cfg.validationAction})
+		}
+
+		return db.RunCommand(ctx, command).Err()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create collection: %s", err)
 	}
 
 	return nil
 }
+
+// isNamespaceExists reports whether err is the server refusing to create an existing collection.
+func isNamespaceExists(err error) bool {
+	var commandError mongo.CommandError
+
+	return errors.As(err, &commandError) && commandError.Code == namespaceExistsCode
+}
@@ -0,0 +1,53 @@
+package mongostorage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// adminDatabase is the name of the database Admin runs its commands against.
+const adminDatabase = "admin"
+
+// Admin runs administrative commands against the admin database of the client the storage is connected to.
+// The connecting user needs the inprog and killop privileges, e.g. the clusterMonitor and hostManager roles.
+type Admin struct {
+	database *mongo.Database
+}
+
+// NewAdmin creates new admin wrapper for the client
+func NewAdmin(client *mongo.Client) *Admin {
+	return &Admin{database: client.Database(adminDatabase)}
+}
+
+// Admin returns the admin wrapper sharing the connection of the storage
+func (s *Storage) Admin() *Admin {
+	return NewAdmin(s.database.Client())
+}
+
+// CurrentOps returns the operations in progress on the server for all users, as reported by $currentOp.
+// The opid of each can be passed to KillOp.
+func (a *Admin) CurrentOps(ctx context.Context) (ops []bson.M, err error) {
+	defer wrapError(&err, "CurrentOps", adminDatabase)
+
+	pipeline := mongo.Pipeline{{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: true}}}}}
+	cursor, err := a.database.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	ops = make([]bson.M, 0)
+	if err = cursor.All(ctx, &ops); err != nil {
+		return nil, err
+	}
+
+	return ops, nil
+}
+
+// KillOp terminates the operation with the given opid, as returned by CurrentOps.
+func (a *Admin) KillOp(ctx context.Context, opid interface{}) (err error) {
+	defer wrapError(&err, "KillOp", adminDatabase)
+
+	return a.database.RunCommand(ctx, bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opid}}).Err()
+}
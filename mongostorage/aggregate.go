@@ -0,0 +1,146 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Aggregate runs the pipeline on the collection and decodes the resulting documents into the slice dest points to.
+// A pipeline ending with $out or $merge writes its results to another collection and leaves dest empty; see
+// AggregateTo.
+func (s *Storage) Aggregate(ctx context.Context, collection string, pipeline mongo.Pipeline, dest interface{}) (err error) {
+	defer wrapError(&err, "Aggregate", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	coll := s.database.Collection(collection)
+	if writesOutput(pipeline) {
+		coll = s.writeCollection(collection)
+	}
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	return decodeCursor(ctx, collection, cursor, dest, nil)
+}
+
+// AggregateTo runs a pipeline ending with $out or $merge on the collection, e.g. to materialize a rollup into another
+// collection. Pipelines not ending with one of them are rejected, since their results would be lost.
+func (s *Storage) AggregateTo(ctx context.Context, collection string, pipeline mongo.Pipeline) (err error) {
+	defer wrapError(&err, "AggregateTo", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if !writesOutput(pipeline) {
+		return errors.New("pipeline must end with a $out or $merge stage")
+	}
+
+	cursor, err := s.writeCollection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close(ctx)
+}
+
+// writesOutput reports whether the last stage of the pipeline writes the results to a collection.
+func writesOutput(pipeline mongo.Pipeline) bool {
+	if len(pipeline) == 0 {
+		return false
+	}
+
+	stage := pipeline[len(pipeline)-1]
+
+	return len(stage) > 0 && (stage[0].Key == "$out" || stage[0].Key == "$merge")
+}
+
+// FindOrphans returns documents of collection whose field references an _id that doesn't exist in referencedCollection.
+// Documents without the field are not considered. A zero limit returns all orphans.
+func (s *Storage) FindOrphans(ctx context.Context, collection, field, referencedCollection string, limit uint64) (orphans []bson.M, err error) {
+	defer wrapError(&err, "FindOrphans", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	const joined = "__referenced"
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$exists": true, "$ne": nil}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         referencedCollection,
+			"localField":   field,
+			"foreignField": "_id",
+			"as":           joined,
+		}}},
+		{{Key: "$match", Value: bson.M{joined: bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{joined: 0}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: int64(limit)}})
+	}
+
+	cursor, err := s.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	orphans = []bson.M{}
+	if err = cursor.All(ctx, &orphans); err != nil {
+		return nil, err
+	}
+
+	return orphans, nil
+}
+
+// DistinctCombinationCount returns the number of distinct combinations of values of fields among documents matching filter.
+func (s *Storage) DistinctCombinationCount(ctx context.Context, collection string, fields []string, filter interface{}) (count uint64, err error) {
+	defer wrapError(&err, "DistinctCombinationCount", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	// group keys can't contain dots, so nested fields are keyed by position
+	key := bson.D{}
+	for i, field := range fields {
+		key = append(key, bson.E{Key: fmt.Sprintf("f%d", i), Value: "$" + field})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": key}}},
+		{{Key: "$count", Value: "count"}},
+	}
+
+	cursor, err := s.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	var result []struct {
+		Count int64 `bson:"count"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return uint64(result[0].Count), nil
+}
@@ -0,0 +1,89 @@
+package mongostorage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddToSet adds value to the array field of every document matching filter that doesn't contain it yet.
+func (s *Storage) AddToSet(ctx context.Context, collection string, filter interface{}, field string, value interface{}) (modified int64, err error) {
+	defer wrapError(&err, "AddToSet", collection)
+
+	_, modified, err = s.updateMany(ctx, collection, filter, bson.M{"$addToSet": bson.M{field: value}})
+
+	return modified, err
+}
+
+// RemoveFromSet removes every occurrence of value from the array field of documents matching filter.
+func (s *Storage) RemoveFromSet(ctx context.Context, collection string, filter interface{}, field string, value interface{}) (modified int64, err error) {
+	defer wrapError(&err, "RemoveFromSet", collection)
+
+	_, modified, err = s.updateMany(ctx, collection, filter, bson.M{"$pull": bson.M{field: value}})
+
+	return modified, err
+}
+
+// PushToArray appends value to the array field of the document with docID, creating the array when the field is
+// missing. Pass a slice to append a single element holding it; use PushEachToArray to append several elements.
+func (s *Storage) PushToArray(ctx context.Context, collection string, docID primitive.ObjectID, field string, value interface{}) (modified int64, err error) {
+	defer wrapError(&err, "PushToArray", collection)
+
+	return s.update(ctx, collection, docID, bson.M{"$push": bson.M{field: value}})
+}
+
+// PushEachToArray appends every one of values, in order, to the array field of the document with docID.
+func (s *Storage) PushEachToArray(ctx context.Context, collection string, docID primitive.ObjectID, field string, values []interface{}) (modified int64, err error) {
+	defer wrapError(&err, "PushEachToArray", collection)
+
+	return s.update(ctx, collection, docID, bson.M{"$push": bson.M{field: bson.M{"$each": values}}})
+}
+
+// PullFromArray removes every element equal to value from the array field of the document with docID. A condition
+// such as bson.M{"$lt": 10} removes every element satisfying it instead.
+func (s *Storage) PullFromArray(ctx context.Context, collection string, docID primitive.ObjectID, field string, value interface{}) (modified int64, err error) {
+	defer wrapError(&err, "PullFromArray", collection)
+
+	return s.update(ctx, collection, docID, bson.M{"$pull": bson.M{field: value}})
+}
+
+// AddToSetBy appends element to the array field of the document with docID unless an element with the same value of
+// keyField is already there, e.g. keeping one entry per "userId". The check and the append are a single atomic
+// update. It reports whether the element was added; false is also returned when there's no such document.
+func (s *Storage) AddToSetBy(ctx context.Context, collection string, docID primitive.ObjectID, arrayField, keyField string, element interface{}) (added bool, err error) {
+	defer wrapError(&err, "AddToSetBy", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return false, err
+	}
+
+	doc, err := toDocument(element)
+	if err != nil {
+		return false, err
+	}
+
+	var key interface{}
+	found := false
+	for _, elem := range doc {
+		if elem.Key == keyField {
+			key, found = elem.Value, true
+			break
+		}
+	}
+	if !found {
+		return false, fmt.Errorf("element has no %s field", keyField)
+	}
+
+	filter := bson.M{"_id": docID, arrayField: bson.M{"$not": bson.M{"$elemMatch": bson.M{keyField: key}}}}
+	result, err := s.writeCollection(collection).UpdateOne(ctx, filter, bson.M{"$push": bson.M{arrayField: doc}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
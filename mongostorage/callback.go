@@ -0,0 +1,15 @@
+package mongostorage
+
+import "fmt"
+
+// callSafely invokes a user-supplied callback and converts a panic into an error wrapping ErrCallbackPanicked,
+// so the caller can still release cursors, sessions and change streams it holds.
+func callSafely(fn func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%w: %v", ErrCallbackPanicked, recovered)
+		}
+	}()
+
+	return fn()
+}
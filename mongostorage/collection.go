@@ -0,0 +1,36 @@
+package mongostorage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateCollectionWithPreImages creates the collection with change stream pre- and post-images enabled, so change
+// events can carry the document as it was before the change (fullDocumentBeforeChange). Requires MongoDB 6.0+.
+func (s *Storage) CreateCollectionWithPreImages(ctx context.Context, collection string) (err error) {
+	defer wrapError(&err, "CreateCollectionWithPreImages", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	createOptions := options.CreateCollection().SetChangeStreamPreAndPostImages(bson.M{"enabled": true})
+
+	return s.database.CreateCollection(ctx, collection, createOptions)
+}
+
+// CollectionExists reports whether the database has a collection, or a view, with the given name.
+func (s *Storage) CollectionExists(ctx context.Context, name string) (exists bool, err error) {
+	defer wrapError(&err, "CollectionExists", name)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	names, err := s.database.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return false, err
+	}
+
+	return len(names) > 0, nil
+}
@@ -0,0 +1,137 @@
+package mongostorage_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phoenixTW/go-mongodb-client/mongodb"
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const cancellationCollection = "cancellation"
+
+var (
+	databaseCheck sync.Once
+	databaseErr   error
+)
+
+// cancellationStorage returns a storage over a collection of documents, skipping the test without a database.
+func cancellationStorage(t *testing.T, documents int) *mongostorage.Storage {
+	t.Helper()
+
+	testDB, err := mongodb.NewTestDatabase(mongodb.GetMongoDSN(), "go_mongodb_client_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = testDB.MongoClient.Disconnect(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// checked once, so the tests don't all wait for an absent database
+	databaseCheck.Do(func() { databaseErr = testDB.MongoClient.Ping(ctx, nil) })
+	if databaseErr != nil {
+		t.Skipf("no database at %s: %v", mongodb.GetMongoDSN(), databaseErr)
+	}
+
+	storage := mongostorage.New(testDB.MongoClient.Database("go_mongodb_client_test"))
+	_, err = storage.Truncate(ctx, cancellationCollection)
+	require.NoError(t, err)
+
+	docs := make([]interface{}, 0, documents)
+	for i := 0; i < documents; i++ {
+		docs = append(docs, bson.M{"n": i})
+	}
+	_, err = storage.InsertMany(ctx, cancellationCollection, docs)
+	require.NoError(t, err)
+
+	return storage
+}
+
+func TestFindAllStopsWhenContextIsCanceled(t *testing.T) {
+	storage := cancellationStorage(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var docs []bson.M
+	err := storage.FindAll(ctx, cancellationCollection, bson.M{}, &docs)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestForEachStopsWhenContextIsCanceled(t *testing.T) {
+	storage := cancellationStorage(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	visited := 0
+	err := storage.ForEach(ctx, cancellationCollection, bson.M{}, func(raw bson.Raw) error {
+		visited++
+		cancel()
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, visited)
+}
+
+func TestForEachBatchStopsWhenContextIsCanceled(t *testing.T) {
+	storage := cancellationStorage(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batches := 0
+	err := storage.ForEachBatch(ctx, cancellationCollection, nil, 2, func(batch []bson.Raw) error {
+		batches++
+		cancel()
+
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, batches)
+}
+
+// cancelingWriter cancels the context once the first line is written.
+type cancelingWriter struct {
+	cancel context.CancelFunc
+	lines  int
+}
+
+func (w *cancelingWriter) Write(p []byte) (int, error) {
+	w.lines++
+	w.cancel()
+
+	return len(p), nil
+}
+
+func TestExportsStopWhenContextIsCanceled(t *testing.T) {
+	storage := cancellationStorage(t, 10)
+
+	exports := map[string]func(ctx context.Context, w io.Writer) error{
+		"ExportCollectionJSON": func(ctx context.Context, w io.Writer) error {
+			_, err := storage.ExportCollectionJSON(ctx, cancellationCollection, w)
+			return err
+		},
+		"ExportStable": func(ctx context.Context, w io.Writer) error {
+			_, err := storage.ExportStable(ctx, cancellationCollection, bson.M{}, w)
+			return err
+		},
+	}
+	for name, export := range exports {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			w := &cancelingWriter{cancel: cancel}
+			err := export(ctx, w)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, 1, w.lines)
+		})
+	}
+}
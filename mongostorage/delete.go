@@ -0,0 +1,76 @@
+package mongostorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeleteManyBatched deletes documents matching filter in batches of up to batchSize, so a large deletion is split
+// into many small oplog entries instead of a single long-running operation. It returns the number of deleted documents,
+// including those deleted before an error.
+func (s *Storage) DeleteManyBatched(ctx context.Context, collection string, filter interface{}, batchSize int) (deletedCount int64, err error) {
+	defer wrapError(&err, "DeleteManyBatched", collection)
+
+	return s.deleteManyThrottled(ctx, collection, filter, batchSize, 0)
+}
+
+// DeleteManyThrottled works like DeleteManyBatched, but pauses between batches to give secondaries time to catch up
+// and keep replication lag under control. The pause is cut short when ctx is done.
+func (s *Storage) DeleteManyThrottled(ctx context.Context, collection string, filter interface{}, batchSize int, pause time.Duration) (deletedCount int64, err error) {
+	defer wrapError(&err, "DeleteManyThrottled", collection)
+
+	return s.deleteManyThrottled(ctx, collection, filter, batchSize, pause)
+}
+
+// deleteManyThrottled deletes documents matching filter in batches of up to batchSize, pausing between them.
+func (s *Storage) deleteManyThrottled(ctx context.Context, collection string, filter interface{}, batchSize int, pause time.Duration) (deletedCount int64, err error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	coll := s.writeCollection(collection)
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(int64(batchSize))
+	for {
+		cursor, err := coll.Find(ctx, filter, findOptions)
+		if err != nil {
+			return deletedCount, err
+		}
+
+		var batch []struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err = cursor.All(ctx, &batch); err != nil {
+			return deletedCount, err
+		}
+		if len(batch) == 0 {
+			return deletedCount, nil
+		}
+
+		ids := make(bson.A, 0, len(batch))
+		for _, doc := range batch {
+			ids = append(ids, doc.ID)
+		}
+
+		// documents changed since they were found must still match
+		batchFilter := bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": ids}}}}
+		result, err := coll.DeleteMany(ctx, batchFilter)
+		if err != nil {
+			return deletedCount, err
+		}
+		deletedCount += result.DeletedCount
+
+		if len(batch) < batchSize {
+			return deletedCount, nil
+		}
+		if pause > 0 && !sleep(ctx, pause) {
+			return deletedCount, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package mongostorage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// toDocument converts any document accepted by the driver into an ordered bson.D.
+func toDocument(v interface{}) (bson.D, error) {
+	if doc, ok := v.(bson.D); ok {
+		return append(bson.D(nil), doc...), nil
+	}
+
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err = bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// isPipeline reports whether an update is given as an aggregation pipeline rather than an update document.
+func isPipeline(update interface{}) bool {
+	if update == nil {
+		return false
+	}
+	if _, ok := update.(bson.D); ok {
+		return false
+	}
+
+	kind := reflect.TypeOf(update).Kind()
+
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// setField returns the document with key set to value. An existing non-empty value is only replaced when overwrite is set.
+func setField(document interface{}, key string, value interface{}, overwrite bool) (bson.D, error) {
+	doc, err := toDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, elem := range doc {
+		if elem.Key != key {
+			continue
+		}
+
+		if overwrite || isEmptyValue(elem.Value) {
+			doc[i].Value = value
+		}
+
+		return doc, nil
+	}
+
+	return append(doc, bson.E{Key: key, Value: value}), nil
+}
+
+// setOperatorField adds key: value under the given update operator, e.g. {$set: {key: value}}.
+// Pipeline updates get an extra stage instead, so only stage-compatible operators such as $set may be used with them.
+func setOperatorField(update interface{}, operator, key string, value interface{}) (interface{}, error) {
+	if isPipeline(update) {
+		stages := reflect.ValueOf(update)
+		pipeline := make([]interface{}, 0, stages.Len()+1)
+		for i := 0; i < stages.Len(); i++ {
+			pipeline = append(pipeline, stages.Index(i).Interface())
+		}
+
+		return append(pipeline, bson.D{{Key: operator, Value: bson.D{{Key: key, Value: value}}}}), nil
+	}
+
+	doc, err := toDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, elem := range doc {
+		if elem.Key != operator {
+			continue
+		}
+
+		fields, err := setField(elem.Value, key, value, true)
+		if err != nil {
+			return nil, err
+		}
+		doc[i].Value = fields
+
+		return doc, nil
+	}
+
+	return append(doc, bson.E{Key: operator, Value: bson.D{{Key: key, Value: value}}}), nil
+}
+
+// isEmptyValue reports whether a decoded value is null or the zero time, as written for unset time.Time fields.
+func isEmptyValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return true
+	case primitive.DateTime:
+		return value.Time().IsZero()
+	}
+
+	return false
+}
+
+// decodeCursor decodes every document of the cursor, read from collection, into the slice dest points to, calling
+// each with the raw document after it's decoded, and closes the cursor. Decoding failures are returned as DecodeError.
+func decodeCursor(ctx context.Context, collection string, cursor *mongo.Cursor, dest interface{}, each func(raw bson.Raw)) error {
+	defer cursor.Close(ctx)
+
+	if err := checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	sliceValue := reflect.ValueOf(dest).Elem()
+	sliceValue.Set(reflect.MakeSlice(sliceValue.Type(), 0, cursor.RemainingBatchLength()))
+	elemType := sliceValue.Type().Elem()
+	for cursor.Next(ctx) {
+		// Next only consults ctx when fetching a new batch
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		elem := reflect.New(elemType)
+		if err := cursor.Decode(elem.Interface()); err != nil {
+			return decodeError(err, collection, elem.Interface())
+		}
+		sliceValue.Set(reflect.Append(sliceValue, elem.Elem()))
+
+		if each != nil {
+			each(cursor.Current)
+		}
+	}
+
+	return cursor.Err()
+}
+
+// exceedsDepth reports whether the document has more than maxDepth levels of nesting, the document itself being
+// the first one. Arrays are encoded as documents, so they count as a level too.
+func exceedsDepth(doc bson.Raw, maxDepth int) bool {
+	if maxDepth < 1 {
+		return true
+	}
+
+	elements, _ := doc.Elements()
+	for _, elem := range elements {
+		value := elem.Value()
+		switch value.Type {
+		case bsontype.EmbeddedDocument:
+			if exceedsDepth(value.Document(), maxDepth-1) {
+				return true
+			}
+		case bsontype.Array:
+			if exceedsDepth(bson.Raw(value.Array()), maxDepth-1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkSliceDestination verifies dest can receive many documents, i.e. is a non-nil pointer to a slice.
+func checkSliceDestination(dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() || destValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w, got %T", ErrInvalidDestination, dest)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package mongostorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Kinds of schema drift reported by DetectSchemaDrift.
+const (
+	DriftUnexpectedField = "unexpected field"
+	DriftMissingField    = "missing field"
+	DriftWrongType       = "wrong type"
+)
+
+// DriftReport describes a field of a sampled document that deviates from the expected schema.
+type DriftReport struct {
+	DocumentID interface{}
+	Field      string
+	Kind       string
+	// Expected and Actual are BSON type aliases as used by $type, e.g. "string" or "objectId"
+	Expected string
+	Actual   string
+}
+
+// typeAliases maps BSON types to the aliases accepted by $type.
+var typeAliases = map[bsontype.Type]string{
+	bsontype.Double:           "double",
+	bsontype.String:           "string",
+	bsontype.EmbeddedDocument: "object",
+	bsontype.Array:            "array",
+	bsontype.Binary:           "binData",
+	bsontype.Undefined:        "undefined",
+	bsontype.ObjectID:         "objectId",
+	bsontype.Boolean:          "bool",
+	bsontype.DateTime:         "date",
+	bsontype.Null:             "null",
+	bsontype.Regex:            "regex",
+	bsontype.DBPointer:        "dbPointer",
+	bsontype.JavaScript:       "javascript",
+	bsontype.Symbol:           "symbol",
+	bsontype.CodeWithScope:    "javascriptWithScope",
+	bsontype.Int32:            "int",
+	bsontype.Timestamp:        "timestamp",
+	bsontype.Int64:            "long",
+	bsontype.Decimal128:       "decimal",
+	bsontype.MinKey:           "minKey",
+	bsontype.MaxKey:           "maxKey",
+}
+
+// DetectSchemaDrift compares up to sampleSize randomly sampled documents against the expected top-level fields and
+// reports unexpected fields, missing fields and fields of the wrong type. expected maps field names to $type aliases,
+// e.g. bson.M{"name": "string", "age": "int"}; "number" matches any numeric type. _id is always allowed.
+func (s *Storage) DetectSchemaDrift(ctx context.Context, collection string, sampleSize uint64, expected bson.M) (reports []DriftReport, err error) {
+	defer wrapError(&err, "DetectSchemaDrift", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	for field, alias := range expected {
+		if _, ok := alias.(string); !ok {
+			return nil, fmt.Errorf("expected type of %s must be a type alias, got %T", field, alias)
+		}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$sample", Value: bson.M{"size": int64(sampleSize)}}}}
+	cursor, err := s.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// sorted, so missing fields are reported in a stable order
+	fields := make([]string, 0, len(expected))
+	for field := range expected {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	reports = []DriftReport{}
+	for cursor.Next(ctx) {
+		reports = append(reports, documentDrift(cursor.Current, fields, expected)...)
+	}
+
+	return reports, cursor.Err()
+}
+
+// documentDrift returns the deviations of a single document from the expected fields.
+func documentDrift(doc bson.Raw, fields []string, expected bson.M) []DriftReport {
+	docID := doc.Lookup("_id")
+
+	var reports []DriftReport
+	seen := map[string]bool{}
+	elements, _ := doc.Elements()
+	for _, elem := range elements {
+		field := elem.Key()
+		seen[field] = true
+
+		actual := typeAliases[elem.Value().Type]
+		alias, ok := expected[field]
+		switch {
+		case !ok && field != "_id":
+			reports = append(reports, DriftReport{DocumentID: docID, Field: field, Kind: DriftUnexpectedField, Actual: actual})
+		case ok && !matchesTypeAlias(alias.(string), actual):
+			reports = append(reports, DriftReport{DocumentID: docID, Field: field, Kind: DriftWrongType, Expected: alias.(string), Actual: actual})
+		}
+	}
+
+	for _, field := range fields {
+		if !seen[field] {
+			reports = append(reports, DriftReport{DocumentID: docID, Field: field, Kind: DriftMissingField, Expected: expected[field].(string)})
+		}
+	}
+
+	return reports
+}
+
+// matchesTypeAlias reports whether a value of type alias actual satisfies the expected alias.
+func matchesTypeAlias(expected, actual string) bool {
+	if expected == "number" {
+		return actual == "double" || actual == "int" || actual == "long" || actual == "decimal"
+	}
+
+	return expected == actual
+}
@@ -0,0 +1,83 @@
+package mongostorage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotFound is returned by single-document operations when no document matched, wrapping mongo.ErrNoDocuments.
+var ErrNotFound = errors.New("document not found")
+
+// ErrReadOnly is returned by every write operation of ReadOnlyStorage.
+var ErrReadOnly = errors.New("storage is read-only")
+
+// ErrInvalidID is returned by ID-based operations given a zero ObjectID, which usually comes from ObjectID
+// silently failing to parse its input.
+var ErrInvalidID = errors.New("invalid document ID")
+
+// ErrInvalidDestination is returned when a destination for many documents is not a non-nil pointer to a slice.
+var ErrInvalidDestination = errors.New("destination must be a non-nil pointer to a slice")
+
+// ErrIndexNotFound is wrapped by errors caused by a missing index.
+var ErrIndexNotFound = errors.New("index not found")
+
+// ErrCallbackPanicked is wrapped by the error returned when a user-supplied callback panics.
+var ErrCallbackPanicked = errors.New("callback panicked")
+
+// ErrVersionConflict is returned by UpdateWithVersion when the document doesn't have the expected version,
+// i.e. it was changed concurrently or doesn't exist.
+var ErrVersionConflict = errors.New("document version conflict")
+
+// ErrDocumentTooDeep is returned when a written document is nested deeper than allowed by WithMaxDocumentDepth.
+var ErrDocumentTooDeep = errors.New("document is nested too deep")
+
+// DecodeError is returned when a document was read but doesn't fit the destination, e.g. because a field has another
+// type than the destination struct expects. Unlike other read errors, it points at the data rather than the
+// connection, and retrying doesn't help.
+type DecodeError struct {
+	// Collection is the collection the document was read from.
+	Collection string
+	// Type is the type of the destination the document was decoded into.
+	Type reflect.Type
+	// Err is the error of the decoder.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %s document into %s: %v", e.Collection, e.Type, e.Err)
+}
+
+// Unwrap returns the error of the decoder.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError wraps a non-nil err returned when decoding a document of collection into dest with DecodeError.
+func decodeError(err error, collection string, dest interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	return &DecodeError{Collection: collection, Type: reflect.TypeOf(dest), Err: err}
+}
+
+// notFound wraps mongo.ErrNoDocuments with ErrNotFound, leaving other errors as they are.
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	return err
+}
+
+// wrapError annotates a non-nil *err with the operation and collection it came from, e.g. "FindOne(users): ...",
+// keeping the original error available to errors.Is and errors.As.
+func wrapError(err *error, operation, collection string) {
+	if *err != nil {
+		*err = fmt.Errorf("%s(%s): %w", operation, collection, *err)
+	}
+}
@@ -0,0 +1,57 @@
+package mongostorage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Verbosity modes of Explain.
+const (
+	ExplainQueryPlanner      = "queryPlanner"
+	ExplainExecutionStats    = "executionStats"
+	ExplainAllPlansExecution = "allPlansExecution"
+)
+
+// ExplainOption configures optional behaviour of Explain.
+type ExplainOption func(*explainConfig)
+
+type explainConfig struct {
+	verbosity string
+}
+
+// WithVerbosity sets how much Explain reports, one of ExplainQueryPlanner, ExplainExecutionStats and
+// ExplainAllPlansExecution. Only the latter two run the query. Defaults to ExplainQueryPlanner.
+func WithVerbosity(verbosity string) ExplainOption {
+	return func(cfg *explainConfig) {
+		cfg.verbosity = verbosity
+	}
+}
+
+// Explain returns the execution plan of a find with filter on the collection, e.g. to check the winning plan
+// uses an IXSCAN rather than a COLLSCAN.
+func (s *Storage) Explain(ctx context.Context, collection string, filter interface{}, opts ...ExplainOption) (plan bson.M, err error) {
+	defer wrapError(&err, "Explain", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	cfg := explainConfig{verbosity: ExplainQueryPlanner}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	command := bson.D{
+		{Key: "explain", Value: bson.D{{Key: "find", Value: collection}, {Key: "filter", Value: filter}}},
+		{Key: "verbosity", Value: cfg.verbosity},
+	}
+
+	if err = s.database.RunCommand(ctx, command).Decode(&plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
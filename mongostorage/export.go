@@ -0,0 +1,81 @@
+package mongostorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportCollectionJSON writes every document of the collection to w as canonical extended JSON, one document per line.
+// It returns the number of exported documents.
+func (s *Storage) ExportCollectionJSON(ctx context.Context, collection string, w io.Writer) (exported int64, err error) {
+	defer wrapError(&err, "ExportCollectionJSON", collection)
+
+	cursor, err := s.database.Collection(collection).Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	return writeExtJSON(ctx, cursor, w)
+}
+
+// ExportStable works like ExportCollectionJSON for the documents matching filter, but always streams them in _id order,
+// so exporting unchanged data produces byte-identical output.
+func (s *Storage) ExportStable(ctx context.Context, collection string, filter interface{}, w io.Writer) (exported int64, err error) {
+	defer wrapError(&err, "ExportStable", collection)
+
+	return s.exportStable(ctx, collection, filter, w)
+}
+
+// exportStable writes the documents matching filter to w in _id order.
+func (s *Storage) exportStable(ctx context.Context, collection string, filter interface{}, w io.Writer) (int64, error) {
+	cursor, err := s.database.Collection(collection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+
+	return writeExtJSON(ctx, cursor, w)
+}
+
+// CollectionChecksum returns a hex-encoded SHA-256 over the documents matching filter, streamed in _id order as
+// canonical extended JSON. Collections holding identical documents, field order included, produce the same checksum.
+func (s *Storage) CollectionChecksum(ctx context.Context, collection string, filter interface{}) (checksum string, err error) {
+	defer wrapError(&err, "CollectionChecksum", collection)
+
+	hash := sha256.New()
+	if _, err = s.exportStable(ctx, collection, filter, hash); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeExtJSON drains the cursor into w as canonical extended JSON lines and closes it.
+func writeExtJSON(ctx context.Context, cursor *mongo.Cursor, w io.Writer) (int64, error) {
+	defer cursor.Close(ctx)
+
+	var exported int64
+	for cursor.Next(ctx) {
+		// Next only consults ctx when fetching a new batch
+		if err := ctx.Err(); err != nil {
+			return exported, err
+		}
+
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return exported, err
+		}
+
+		if _, err = w.Write(append(line, '\n')); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+
+	return exported, cursor.Err()
+}
@@ -0,0 +1,57 @@
+package mongostorage
+
+import (
+	"context"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FindByIDs returns the documents with the given ids into destination, aligned with ids: the element at index i is
+// the document with ids[i], or the zero value, e.g. nil for a slice of pointers, when there's no such document.
+// This is what batch loaders such as GraphQL dataloaders expect.
+func (s *Storage) FindByIDs(ctx context.Context, collection string, ids []primitive.ObjectID, dest interface{}) (err error) {
+	defer wrapError(&err, "FindByIDs", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	sliceValue := reflect.ValueOf(dest).Elem()
+	if len(ids) == 0 {
+		sliceValue.Set(reflect.MakeSlice(sliceValue.Type(), 0, 0))
+
+		return nil
+	}
+
+	cursor, err := s.database.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+
+	found := reflect.New(sliceValue.Type())
+	positions := map[primitive.ObjectID]int{}
+	err = decodeCursor(ctx, collection, cursor, found.Interface(), func(raw bson.Raw) {
+		if id, ok := raw.Lookup("_id").ObjectIDOK(); ok {
+			positions[id] = len(positions)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	docs := found.Elem()
+	ordered := reflect.MakeSlice(sliceValue.Type(), len(ids), len(ids))
+	for i, id := range ids {
+		if position, ok := positions[id]; ok {
+			ordered.Index(i).Set(docs.Index(position))
+		}
+	}
+	sliceValue.Set(ordered)
+
+	return nil
+}
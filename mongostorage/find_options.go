@@ -0,0 +1,143 @@
+package mongostorage
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// FindOption configures optional behaviour of the find operations.
+type FindOption func(*findConfig)
+
+type findConfig struct {
+	projection     interface{}
+	readPreference *readpref.ReadPref
+	collation      *options.Collation
+	hint           interface{}
+	sort           bson.D
+	withoutTotal   bool
+}
+
+// WithProjection limits the returned fields, e.g. bson.M{"name": 1} or bson.M{"blob": 0}.
+// It doesn't affect the total counted by FindMany.
+func WithProjection(projection bson.M) FindOption {
+	return func(cfg *findConfig) {
+		cfg.projection = projection
+	}
+}
+
+// WithReadPreference routes the query according to readPreference, e.g. readpref.SecondaryPreferred() for reads
+// that tolerate slightly stale data. Without it queries use the client read preference, primary unless configured
+// otherwise. Inside RunInTransaction queries always go to the primary.
+func WithReadPreference(readPreference *readpref.ReadPref) FindOption {
+	return func(cfg *findConfig) {
+		cfg.readPreference = readPreference
+	}
+}
+
+// WithSecondaryPreferred serves the query from a secondary when one is available, e.g. for heavy exports that
+// shouldn't slow down writes on the primary, at the cost of possibly stale data. It's a shorthand for
+// WithReadPreference(readpref.SecondaryPreferred()); inside RunInTransaction queries still go to the primary.
+func WithSecondaryPreferred() FindOption {
+	return WithReadPreference(readpref.SecondaryPreferred())
+}
+
+// WithCollation compares strings according to collation, e.g. &options.Collation{Locale: "en", Strength: 2} for
+// case-insensitive matching. It's also used for the total counted by FindMany. Queries can only use indexes
+// created with the same collation, so a matching collated index is needed for them to be fast.
+func WithCollation(collation *options.Collation) FindOption {
+	return func(cfg *findConfig) {
+		cfg.collation = collation
+	}
+}
+
+// WithHint forces the query to use the given index, either by name or by key specification, e.g. "status_1" or
+// bson.D{{Key: "status", Value: 1}}. It's also used for the total counted by FindMany. The query fails when
+// there's no such index.
+func WithHint(hint interface{}) FindOption {
+	return func(cfg *findConfig) {
+		cfg.hint = hint
+	}
+}
+
+// WithSort orders the results, given as a comma-separated list of fields, each optionally prefixed with "-" for
+// descending order, e.g. "status,-createdAt".
+func WithSort(sort string) FindOption {
+	return func(cfg *findConfig) {
+		cfg.sort = parseSort(sort)
+	}
+}
+
+// WithoutTotal makes FindMany skip counting the matching documents and return a zero total, saving a query when
+// the total isn't shown, e.g. for infinite scrolling. Other operations ignore it.
+func WithoutTotal() FindOption {
+	return func(cfg *findConfig) {
+		cfg.withoutTotal = true
+	}
+}
+
+func newFindConfig(opts []FindOption) findConfig {
+	var cfg findConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+func (cfg findConfig) collectionOptions() *options.CollectionOptions {
+	collectionOptions := options.Collection()
+	if cfg.readPreference != nil {
+		collectionOptions.SetReadPreference(cfg.readPreference)
+	}
+
+	return collectionOptions
+}
+
+func (cfg findConfig) findOneOptions() *options.FindOneOptions {
+	findOneOptions := options.FindOne()
+	if cfg.projection != nil {
+		findOneOptions.SetProjection(cfg.projection)
+	}
+	if cfg.collation != nil {
+		findOneOptions.SetCollation(cfg.collation)
+	}
+	if cfg.hint != nil {
+		findOneOptions.SetHint(cfg.hint)
+	}
+	if len(cfg.sort) > 0 {
+		findOneOptions.SetSort(cfg.sort)
+	}
+
+	return findOneOptions
+}
+
+func (cfg findConfig) findOptions() *options.FindOptions {
+	findOptions := options.Find()
+	if cfg.projection != nil {
+		findOptions.SetProjection(cfg.projection)
+	}
+	if cfg.collation != nil {
+		findOptions.SetCollation(cfg.collation)
+	}
+	if cfg.hint != nil {
+		findOptions.SetHint(cfg.hint)
+	}
+	if len(cfg.sort) > 0 {
+		findOptions.SetSort(cfg.sort)
+	}
+
+	return findOptions
+}
+
+func (cfg findConfig) countOptions() *options.CountOptions {
+	countOptions := options.Count()
+	if cfg.collation != nil {
+		countOptions.SetCollation(cfg.collation)
+	}
+	if cfg.hint != nil {
+		countOptions.SetHint(cfg.hint)
+	}
+
+	return countOptions
+}
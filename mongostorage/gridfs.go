@@ -0,0 +1,60 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFSBucket returns the GridFS bucket with the given name in the database of the storage, for files exceeding
+// the 16MB document size limit. An empty name selects the default "fs" bucket.
+func (s *Storage) GridFSBucket(bucketName string) (*gridfs.Bucket, error) {
+	bucketOptions := options.GridFSBucket()
+	if bucketName != "" {
+		bucketOptions.SetName(bucketName)
+	}
+
+	return gridfs.NewBucket(s.database, bucketOptions)
+}
+
+// UploadFile stores the content of r as a file of the bucket and returns its ID.
+// The GridFS API of the driver doesn't take a context, so only the deadline of ctx bounds the upload.
+func (s *Storage) UploadFile(ctx context.Context, bucketName, filename string, r io.Reader) (primitive.ObjectID, error) {
+	bucket, err := s.GridFSBucket(bucketName)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = bucket.SetWriteDeadline(deadline); err != nil {
+			return primitive.NilObjectID, err
+		}
+	}
+
+	return bucket.UploadFromStream(filename, r)
+}
+
+// DownloadFile writes the content of the file with id from the bucket to w. ErrNotFound is returned when there's
+// no such file. Like UploadFile, only the deadline of ctx bounds the download.
+func (s *Storage) DownloadFile(ctx context.Context, bucketName string, id primitive.ObjectID, w io.Writer) error {
+	bucket, err := s.GridFSBucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = bucket.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	_, err = bucket.DownloadToStream(id, w)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	return err
+}
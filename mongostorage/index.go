@@ -0,0 +1,184 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexOption configures an index created by CreateIndex or CreateIndexes.
+type IndexOption func(*options.IndexOptions)
+
+// Index describes an index for CreateIndexes.
+type Index struct {
+	Keys    bson.D
+	Options []IndexOption
+}
+
+// WithIndexName names the index instead of deriving the name from its keys.
+func WithIndexName(name string) IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetName(name)
+	}
+}
+
+// WithUnique rejects documents duplicating the indexed values.
+func WithUnique() IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetUnique(true)
+	}
+}
+
+// WithExpireAfter makes a TTL index removing documents once the indexed date is older than ttl, truncated to whole
+// seconds; a zero ttl removes them as soon as the date is past, e.g. for an "expiresAt" field. TTL indexes must have
+// a single key, and documents whose field isn't a date, or an array of dates, never expire. See CreateTTLIndex.
+func WithExpireAfter(ttl time.Duration) IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetExpireAfterSeconds(int32(ttl / time.Second))
+	}
+}
+
+// WithPartialFilter only indexes documents matching filter. Combined with WithUnique, values only have to be unique
+// among those documents, e.g. an email unique among bson.M{"deleted": false}. Queries can only use the index when
+// their filter implies the partial filter.
+func WithPartialFilter(filter interface{}) IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetPartialFilterExpression(filter)
+	}
+}
+
+// WithSparse skips documents that don't have the indexed fields.
+func WithSparse() IndexOption {
+	return func(opts *options.IndexOptions) {
+		opts.SetSparse(true)
+	}
+}
+
+// CreateIndex creates an index over keys, e.g. bson.D{{Key: "email", Value: 1}}, and returns its name.
+func (s *Storage) CreateIndex(ctx context.Context, collection string, keys bson.D, opts ...IndexOption) (name string, err error) {
+	defer wrapError(&err, "CreateIndex", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	return s.createIndex(ctx, collection, keys, opts...)
+}
+
+// CreateTTLIndex creates a TTL index on the date field, removing documents ttl after their date, and returns its name.
+func (s *Storage) CreateTTLIndex(ctx context.Context, collection string, field string, ttl time.Duration, opts ...IndexOption) (name string, err error) {
+	defer wrapError(&err, "CreateTTLIndex", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	return s.createIndex(ctx, collection, bson.D{{Key: field, Value: 1}}, append([]IndexOption{WithExpireAfter(ttl)}, opts...)...)
+}
+
+// CreateIndexes creates the indexes in a single command and returns their names in order.
+func (s *Storage) CreateIndexes(ctx context.Context, collection string, indexes []Index) (names []string, err error) {
+	defer wrapError(&err, "CreateIndexes", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	models := make([]mongo.IndexModel, 0, len(indexes))
+	for _, index := range indexes {
+		models = append(models, indexModel(index))
+	}
+
+	return s.database.Collection(collection).Indexes().CreateMany(ctx, models)
+}
+
+// CreateTextIndex creates the text index of the collection over the fields of weights, each field weighted by its
+// value, so e.g. matches in a title weighted 10 rank above matches in a body weighted 1.
+// A collection can have one text index only.
+func (s *Storage) CreateTextIndex(ctx context.Context, collection string, weights map[string]int) (err error) {
+	defer wrapError(&err, "CreateTextIndex", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	fields := make([]string, 0, len(weights))
+	for field := range weights {
+		fields = append(fields, field)
+	}
+	// map order is random, keep the index definition stable
+	sort.Strings(fields)
+
+	keys := bson.D{}
+	indexWeights := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+		indexWeights = append(indexWeights, bson.E{Key: field, Value: weights[field]})
+	}
+
+	_, err = s.createIndex(ctx, collection, keys, func(opts *options.IndexOptions) {
+		opts.SetWeights(indexWeights)
+	})
+
+	return err
+}
+
+// ListIndexes returns the specifications of all indexes of the collection.
+func (s *Storage) ListIndexes(ctx context.Context, collection string) (indexes []bson.M, err error) {
+	defer wrapError(&err, "ListIndexes", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	cursor, err := s.database.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes = []bson.M{}
+	if err = cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	return indexes, nil
+}
+
+// DropIndex drops the index with the given name. An error wrapping ErrIndexNotFound is returned if there's none.
+func (s *Storage) DropIndex(ctx context.Context, collection string, name string) (err error) {
+	defer wrapError(&err, "DropIndex", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	_, err = s.database.Collection(collection).Indexes().DropOne(ctx, name)
+
+	return indexNotFound(err, fmt.Sprintf("index %q", name))
+}
+
+// createIndex creates an index over keys and returns its name.
+func (s *Storage) createIndex(ctx context.Context, collection string, keys bson.D, opts ...IndexOption) (string, error) {
+	return s.database.Collection(collection).Indexes().CreateOne(ctx, indexModel(Index{Keys: keys, Options: opts}))
+}
+
+// indexNotFound wraps a server IndexNotFound error with ErrIndexNotFound.
+func indexNotFound(err error, what string) error {
+	const indexNotFoundCode = 27
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(indexNotFoundCode) {
+		return fmt.Errorf("%s: %w: %w", what, ErrIndexNotFound, err)
+	}
+
+	return err
+}
+
+func indexModel(index Index) mongo.IndexModel {
+	indexOptions := options.Index()
+	for _, opt := range index.Options {
+		opt(indexOptions)
+	}
+
+	return mongo.IndexModel{Keys: index.Keys, Options: indexOptions}
+}
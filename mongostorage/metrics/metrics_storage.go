@@ -0,0 +1,264 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ mongostorage.StorageReaderWriter = (*MetricsStorage)(nil)
+
+// MetricsStorage wraps StorageReaderWriter and records the latency and outcome of every operation to Prometheus,
+// labeled by collection and operation name. It lives in its own package, so users who don't want metrics don't
+// depend on Prometheus.
+//
+// To count retries, wrap the storage with metrics first and pass OnRetry to the retrying storage built on top:
+//
+//	metricsStorage, err := metrics.New(storage, prometheus.DefaultRegisterer)
+//	retryingStorage := mongostorage.NewRetry(metricsStorage, logger, mongostorage.RetryConfig{OnRetry: metricsStorage.OnRetry})
+//
+// Every attempt is then recorded as an operation of its own, and retries are counted by collection and operation.
+type MetricsStorage struct {
+	upstream mongostorage.StorageReaderWriter
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+}
+
+// New creates new mongostorage recording metrics into registerer. When a collector can't be registered, e.g. because
+// New already registered it into registerer, the collectors registered so far are unregistered again and the error
+// is returned, so New can be called again with another registerer.
+func New(upstream mongostorage.StorageReaderWriter, registerer prometheus.Registerer) (*MetricsStorage, error) {
+	s := &MetricsStorage{
+		upstream: upstream,
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mongostorage",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of mongostorage operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"collection", "operation", "outcome"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mongostorage",
+			Name:      "operation_errors_total",
+			Help:      "Number of mongostorage operations that returned an error.",
+		}, []string{"collection", "operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mongostorage",
+			Name:      "retries_total",
+			Help:      "Number of retried mongostorage operation attempts.",
+		}, []string{"collection", "operation"}),
+	}
+
+	collectors := []prometheus.Collector{s.duration, s.errors, s.retries}
+	for i, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			for _, registered := range collectors[:i] {
+				registerer.Unregister(registered)
+			}
+
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// OnRetry counts a retry, to be used as mongostorage.RetryConfig.OnRetry.
+func (s *MetricsStorage) OnRetry(operation, collection string, _ int, _ error) {
+	s.retries.WithLabelValues(collection, operation).Inc()
+}
+
+// FindOne returns a row into destination.
+func (s *MetricsStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	defer s.observe("FindOne", collection, time.Now(), &err)
+
+	return s.upstream.FindOne(ctx, collection, filter, dest, opts...)
+}
+
+// FindAll returns all rows matching filter into destination.
+func (s *MetricsStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	defer s.observe("FindAll", collection, time.Now(), &err)
+
+	return s.upstream.FindAll(ctx, collection, filter, dest, opts...)
+}
+
+// FindMany returns rows into destination.
+func (s *MetricsStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...mongostorage.FindOption) (total uint64, err error) {
+	defer s.observe("FindMany", collection, time.Now(), &err)
+
+	return s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, dest, opts...)
+}
+
+// Count returns the number of documents matching filter.
+func (s *MetricsStorage) Count(ctx context.Context, collection string, filter interface{}) (count uint64, err error) {
+	defer s.observe("Count", collection, time.Now(), &err)
+
+	return s.upstream.Count(ctx, collection, filter)
+}
+
+// EstimatedCount returns the approximate number of documents in the collection.
+func (s *MetricsStorage) EstimatedCount(ctx context.Context, collection string) (count uint64, err error) {
+	defer s.observe("EstimatedCount", collection, time.Now(), &err)
+
+	return s.upstream.EstimatedCount(ctx, collection)
+}
+
+// Distinct returns the unique values of field among documents matching filter.
+func (s *MetricsStorage) Distinct(ctx context.Context, collection string, field string, filter interface{}) (values []interface{}, err error) {
+	defer s.observe("Distinct", collection, time.Now(), &err)
+
+	return s.upstream.Distinct(ctx, collection, field, filter)
+}
+
+// Exists reports whether a document matches filter.
+func (s *MetricsStorage) Exists(ctx context.Context, collection string, filter interface{}) (exists bool, err error) {
+	defer s.observe("Exists", collection, time.Now(), &err)
+
+	return s.upstream.Exists(ctx, collection, filter)
+}
+
+// RunInTransaction encapsulates the function that needs to run in a transaction.
+func (s *MetricsStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...mongostorage.TxnOption) (err error) {
+	defer s.observe("RunInTransaction", "", time.Now(), &err)
+
+	return s.upstream.RunInTransaction(ctx, fn, opts...)
+}
+
+// Insert makes insert into database.
+func (s *MetricsStorage) Insert(ctx context.Context, collection string, document interface{}) (err error) {
+	defer s.observe("Insert", collection, time.Now(), &err)
+
+	return s.upstream.Insert(ctx, collection, document)
+}
+
+// InsertMany inserts documents into database in a single round trip.
+func (s *MetricsStorage) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...mongostorage.InsertManyOption) (insertedIDs []interface{}, err error) {
+	defer s.observe("InsertMany", collection, time.Now(), &err)
+
+	return s.upstream.InsertMany(ctx, collection, documents, opts...)
+}
+
+// Update updates documents in the database.
+func (s *MetricsStorage) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
+	defer s.observe("Update", collection, time.Now(), &err)
+
+	return s.upstream.Update(ctx, collection, docID, update)
+}
+
+// UpdateMany updates all documents matching filter in the database.
+func (s *MetricsStorage) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	defer s.observe("UpdateMany", collection, time.Now(), &err)
+
+	return s.upstream.UpdateMany(ctx, collection, filter, update)
+}
+
+// FindOneAndUpdate atomically updates a single document and decodes it into destination.
+func (s *MetricsStorage) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) (err error) {
+	defer s.observe("FindOneAndUpdate", collection, time.Now(), &err)
+
+	return s.upstream.FindOneAndUpdate(ctx, collection, filter, update, dest, returnNew)
+}
+
+// FindOneAndDelete atomically deletes a single document and decodes it into destination.
+func (s *MetricsStorage) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) (err error) {
+	defer s.observe("FindOneAndDelete", collection, time.Now(), &err)
+
+	return s.upstream.FindOneAndDelete(ctx, collection, filter, sort, dest)
+}
+
+// FindOneAndReplace atomically replaces a single document and decodes it into destination.
+func (s *MetricsStorage) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) (err error) {
+	defer s.observe("FindOneAndReplace", collection, time.Now(), &err)
+
+	return s.upstream.FindOneAndReplace(ctx, collection, filter, replacement, returnNew, upsert, dest)
+}
+
+// Upsert updates or inserts document in the database.
+func (s *MetricsStorage) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
+	defer s.observe("Upsert", collection, time.Now(), &err)
+
+	return s.upstream.Upsert(ctx, collection, docID, update)
+}
+
+// Replace replaces the whole document in the database.
+func (s *MetricsStorage) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	defer s.observe("Replace", collection, time.Now(), &err)
+
+	return s.upstream.Replace(ctx, collection, docID, replacement)
+}
+
+// ReplaceUpsert replaces or inserts the whole document in the database.
+func (s *MetricsStorage) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	defer s.observe("ReplaceUpsert", collection, time.Now(), &err)
+
+	return s.upstream.ReplaceUpsert(ctx, collection, docID, replacement)
+}
+
+// Delete deletes document in the database.
+func (s *MetricsStorage) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
+	defer s.observe("Delete", collection, time.Now(), &err)
+
+	return s.upstream.Delete(ctx, collection, docID)
+}
+
+// DeleteMany deletes filtered documents in the database.
+func (s *MetricsStorage) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
+	defer s.observe("DeleteMany", collection, time.Now(), &err)
+
+	return s.upstream.DeleteMany(ctx, collection, filter)
+}
+
+// Truncate deletes every document of the collection.
+func (s *MetricsStorage) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	defer s.observe("Truncate", collection, time.Now(), &err)
+
+	return s.upstream.Truncate(ctx, collection)
+}
+
+// BulkWrite executes a batch of inserts, updates and deletes in a single command.
+func (s *MetricsStorage) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (result *mongo.BulkWriteResult, err error) {
+	defer s.observe("BulkWrite", collection, time.Now(), &err)
+
+	return s.upstream.BulkWrite(ctx, collection, models, ordered)
+}
+
+// GetDatabaseName returns the name of the current database.
+func (s *MetricsStorage) GetDatabaseName() string {
+	return s.upstream.GetDatabaseName()
+}
+
+// Ping verifies the connection to the database is alive.
+func (s *MetricsStorage) Ping(ctx context.Context) (err error) {
+	defer s.observe("Ping", "", time.Now(), &err)
+
+	return s.upstream.Ping(ctx)
+}
+
+// Close disconnects from the database.
+func (s *MetricsStorage) Close(ctx context.Context) (err error) {
+	defer s.observe("Close", "", time.Now(), &err)
+
+	return s.upstream.Close(ctx)
+}
+
+// DropCollection drops the collection together with its indexes.
+func (s *MetricsStorage) DropCollection(ctx context.Context, collection string) (err error) {
+	defer s.observe("DropCollection", collection, time.Now(), &err)
+
+	return s.upstream.DropCollection(ctx, collection)
+}
+
+// observe records an operation that started at started and finished with *err.
+func (s *MetricsStorage) observe(operation, collection string, started time.Time, err *error) {
+	outcome := "success"
+	if *err != nil {
+		outcome = "error"
+		s.errors.WithLabelValues(collection, operation).Inc()
+	}
+
+	s.duration.WithLabelValues(collection, operation, outcome).Observe(time.Since(started).Seconds())
+}
@@ -0,0 +1,102 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"github.com/phoenixTW/go-mongodb-client/mongostorage/metrics"
+	"github.com/phoenixTW/go-mongodb-client/mongostorage/mock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// findMetric returns the metric of the family named name having all the given label values, or nil.
+func findMetric(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					continue metrics
+				}
+			}
+
+			return metric
+		}
+	}
+
+	return nil
+}
+
+func TestMetricsStorageRecordsOperationsAndRetries(t *testing.T) {
+	calls := 0
+	upstream := &mock.MockedStorageReaderWriter{}
+	upstream.FindMock = func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) error {
+		calls++
+		if calls == 1 {
+			return io.EOF
+		}
+
+		return nil
+	}
+
+	registry := prometheus.NewRegistry()
+	metricsStorage, err := metrics.New(upstream, registry)
+	require.NoError(t, err)
+	storage := mongostorage.NewRetry(metricsStorage, zap.NewNop(), mongostorage.RetryConfig{
+		BaseDelay: time.Millisecond,
+		OnRetry:   metricsStorage.OnRetry,
+	})
+
+	require.NoError(t, storage.FindOne(context.Background(), "users", nil, &struct{}{}))
+
+	for _, outcome := range []string{"error", "success"} {
+		duration := findMetric(t, registry, "mongostorage_operation_duration_seconds",
+			map[string]string{"collection": "users", "operation": "FindOne", "outcome": outcome})
+		require.NotNil(t, duration, outcome)
+		assert.Equal(t, uint64(1), duration.GetHistogram().GetSampleCount(), outcome)
+	}
+
+	errors := findMetric(t, registry, "mongostorage_operation_errors_total",
+		map[string]string{"collection": "users", "operation": "FindOne"})
+	require.NotNil(t, errors)
+	assert.Equal(t, 1.0, errors.GetCounter().GetValue())
+
+	retries := findMetric(t, registry, "mongostorage_retries_total",
+		map[string]string{"collection": "users", "operation": "FindOne"})
+	require.NotNil(t, retries)
+	assert.Equal(t, 1.0, retries.GetCounter().GetValue())
+}
+
+func TestNewUnregistersCollectorsOnError(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	// the last collector registered by New
+	conflicting := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mongostorage",
+		Name:      "retries_total",
+		Help:      "Number of retried mongostorage operation attempts.",
+	}, []string{"collection", "operation"})
+	require.NoError(t, registry.Register(conflicting))
+
+	_, err := metrics.New(&mock.MockedStorageReaderWriter{}, registry)
+	require.Error(t, err)
+
+	// the collectors registered before the failure don't stand in the way of another attempt
+	registry.Unregister(conflicting)
+	_, err = metrics.New(&mock.MockedStorageReaderWriter{}, registry)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,387 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toMap converts any document accepted by the driver into a detached bson.M
+func toMap(document interface{}) (bson.M, error) {
+	if document == nil {
+		return bson.M{}, nil
+	}
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	if err = bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// toRawValue marshals a single value
+func toRawValue(value interface{}) (bson.RawValue, error) {
+	valueType, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+
+	return bson.RawValue{Type: valueType, Value: data}, nil
+}
+
+// lookup returns the value at the dotted path of the document
+func lookup(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// setPath sets the value at the dotted path of the document, creating missing embedded documents
+func setPath(doc bson.M, path string, value interface{}) error {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key]
+		if !ok {
+			child := bson.M{}
+			current[key] = child
+			current = child
+
+			continue
+		}
+
+		child, ok := next.(bson.M)
+		if !ok {
+			return fmt.Errorf("can't set %s: %s is not a document", path, key)
+		}
+		current = child
+	}
+	current[keys[len(keys)-1]] = value
+
+	return nil
+}
+
+// unsetPath removes the value at the dotted path of the document
+func unsetPath(doc bson.M, path string) {
+	keys := strings.Split(path, ".")
+	current := doc
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := current[key].(bson.M)
+		if !ok {
+			return
+		}
+		current = child
+	}
+	delete(current, keys[len(keys)-1])
+}
+
+// matches reports whether the document satisfies the filter. Only equality of fields, dotted paths included,
+// is supported.
+func matches(doc bson.M, filter bson.M) (bool, error) {
+	for path, expected := range filter {
+		if strings.HasPrefix(path, "$") {
+			return false, fmt.Errorf("memory storage doesn't support the %s filter operator", path)
+		}
+		if operators, ok := expected.(bson.M); ok {
+			for key := range operators {
+				if strings.HasPrefix(key, "$") {
+					return false, fmt.Errorf("memory storage doesn't support the %s filter operator", key)
+				}
+			}
+		}
+
+		actual, found := lookup(doc, path)
+		if !found {
+			if expected != nil {
+				return false, nil
+			}
+
+			continue
+		}
+
+		ok, err := equalValues(actual, expected)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// equalValues reports whether actual equals expected like a MongoDB equality match does, i.e. numbers are compared
+// by value and an array matches when any of its elements does.
+func equalValues(actual, expected interface{}) (bool, error) {
+	if elements, ok := actual.(bson.A); ok {
+		if _, expectedArray := expected.(bson.A); !expectedArray {
+			for _, elem := range elements {
+				if ok, err := equalValues(elem, expected); err != nil || ok {
+					return ok, err
+				}
+			}
+
+			return false, nil
+		}
+	}
+
+	actualValue, err := toRawValue(actual)
+	if err != nil {
+		return false, err
+	}
+	expectedValue, err := toRawValue(expected)
+	if err != nil {
+		return false, err
+	}
+
+	return compareValues(actualValue, expectedValue) == 0, nil
+}
+
+// compareValues orders two values, numbers by value and other values of the same type by their natural order
+func compareValues(a, b bson.RawValue) int {
+	aNumber, aIsNumber := number(a)
+	bNumber, bIsNumber := number(b)
+	switch {
+	case aIsNumber && bIsNumber:
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		}
+
+		return 0
+	case a.Type != b.Type:
+		return int(a.Type) - int(b.Type)
+	}
+
+	switch a.Type {
+	case bsontype.String:
+		return strings.Compare(a.StringValue(), b.StringValue())
+	case bsontype.DateTime:
+		return int(a.DateTime() - b.DateTime())
+	case bsontype.Boolean:
+		if a.Boolean() == b.Boolean() {
+			return 0
+		}
+		if b.Boolean() {
+			return -1
+		}
+
+		return 1
+	}
+
+	return bytes.Compare(a.Value, b.Value)
+}
+
+// number returns the numeric value as float64
+func number(value bson.RawValue) (float64, bool) {
+	switch value.Type {
+	case bsontype.Int32:
+		return float64(value.Int32()), true
+	case bsontype.Int64:
+		return float64(value.Int64()), true
+	case bsontype.Double:
+		return value.Double(), true
+	}
+
+	return 0, false
+}
+
+// applyUpdate applies the update operators $set, $unset, $inc and $setOnInsert to the document
+func applyUpdate(doc bson.M, update interface{}, inserting bool) error {
+	operators, err := toMap(update)
+	if err != nil {
+		return err
+	}
+
+	for operator, fields := range operators {
+		values, ok := fields.(bson.M)
+		if !ok {
+			return fmt.Errorf("memory storage doesn't support the update %s", operator)
+		}
+
+		for path, value := range values {
+			switch operator {
+			case "$set":
+				err = setPath(doc, path, value)
+			case "$setOnInsert":
+				if inserting {
+					err = setPath(doc, path, value)
+				}
+			case "$unset":
+				unsetPath(doc, path)
+			case "$inc":
+				current, _ := lookup(doc, path)
+				var sum interface{}
+				if sum, err = add(current, value); err == nil {
+					err = setPath(doc, path, sum)
+				}
+			default:
+				return fmt.Errorf("memory storage doesn't support the %s update operator", operator)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// add returns the sum of two numbers, an integer when both are integers
+func add(current, delta interface{}) (interface{}, error) {
+	if current == nil {
+		current = int32(0)
+	}
+
+	currentValue, err := toRawValue(current)
+	if err != nil {
+		return nil, err
+	}
+	deltaValue, err := toRawValue(delta)
+	if err != nil {
+		return nil, err
+	}
+
+	// integers are added as such, float64 can't hold every int64
+	a, aIsInteger := integer(currentValue)
+	b, bIsInteger := integer(deltaValue)
+	if aIsInteger && bIsInteger {
+		return a + b, nil
+	}
+
+	x, xIsNumber := number(currentValue)
+	y, yIsNumber := number(deltaValue)
+	if !xIsNumber || !yIsNumber {
+		return nil, fmt.Errorf("can't increment %v by %v", current, delta)
+	}
+
+	return x + y, nil
+}
+
+// integer returns the integer value as int64
+func integer(value bson.RawValue) (int64, bool) {
+	switch value.Type {
+	case bsontype.Int32:
+		return int64(value.Int32()), true
+	case bsontype.Int64:
+		return value.Int64(), true
+	}
+
+	return 0, false
+}
+
+// equalityFields returns the fields of the filter that an upserted document starts with
+func equalityFields(filter bson.M) bson.M {
+	doc := bson.M{}
+	for path, value := range filter {
+		_ = setPath(doc, path, value)
+	}
+
+	return doc
+}
+
+// decodeDocument decodes the document of collection into dest, reporting failures as mongostorage.DecodeError
+func decodeDocument(collection string, doc bson.M, dest interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	if err = bson.Unmarshal(raw, dest); err != nil {
+		return &mongostorage.DecodeError{Collection: collection, Type: reflect.TypeOf(dest), Err: err}
+	}
+
+	return nil
+}
+
+// decodeDocuments decodes the documents of collection into the slice dest points to
+func decodeDocuments(collection string, docs []bson.M, dest interface{}) error {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr || destValue.IsNil() || destValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w, got %T", mongostorage.ErrInvalidDestination, dest)
+	}
+
+	sliceValue := destValue.Elem()
+	result := reflect.MakeSlice(sliceValue.Type(), 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(sliceValue.Type().Elem())
+		if err := decodeDocument(collection, doc, elem.Interface()); err != nil {
+			return err
+		}
+		result = reflect.Append(result, elem.Elem())
+	}
+	sliceValue.Set(result)
+
+	return nil
+}
+
+// sortDocuments orders the documents by a sort expression such as "status,-createdAt", keeping the insertion order
+// of equal documents
+func sortDocuments(docs []bson.M, expression string) {
+	var fields []string
+	for _, field := range strings.Split(expression, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, field := range fields {
+			direction := 1
+			if strings.HasPrefix(field, "-") {
+				field, direction = strings.TrimPrefix(field, "-"), -1
+			}
+
+			a, _ := lookup(docs[i], field)
+			b, _ := lookup(docs[j], field)
+			aValue, _ := toRawValue(a)
+			bValue, _ := toRawValue(b)
+			if c := compareValues(aValue, bValue); c != 0 {
+				return c*direction < 0
+			}
+		}
+
+		return false
+	})
+}
+
+// copyDocument returns a deep copy of the document
+func copyDocument(doc bson.M) bson.M {
+	copied, _ := toMap(doc)
+
+	return copied
+}
+
+// newID returns the _id of an inserted document, generating one when it has none
+func newID(doc bson.M) interface{} {
+	if id, ok := doc["_id"]; ok {
+		return id
+	}
+
+	id := primitive.NewObjectID()
+	doc["_id"] = id
+
+	return id
+}
@@ -0,0 +1,660 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ mongostorage.StorageReaderWriter = (*MemoryStorage)(nil)
+
+// MemoryStorage is a working in-memory implementation of StorageReaderWriter for unit tests of CRUD flows.
+// Documents are kept per collection in insertion order. Filters only support equality of fields, dotted paths
+// included, and updates only the $set, $unset, $inc and $setOnInsert operators; anything else is reported as an
+// error rather than silently ignored. Find options are ignored.
+type MemoryStorage struct {
+	mu          sync.RWMutex
+	collections map[string][]bson.M
+}
+
+// NewMemoryStorage creates new empty mongostorage keeping documents in memory
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{collections: map[string][]bson.M{}}
+}
+
+// FindOne returns a row into destination. ErrNotFound is returned when nothing matched.
+func (s *MemoryStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return notFound()
+	}
+
+	return decodeDocument(collection, docs[0], dest)
+}
+
+// FindAll returns all rows matching filter into destination.
+func (s *MemoryStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+	if err != nil {
+		return err
+	}
+
+	return decodeDocuments(collection, docs, dest)
+}
+
+// FindMany returns rows into destination, sorted, skipped and limited like the real storage does.
+func (s *MemoryStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...mongostorage.FindOption) (total uint64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+	if err != nil {
+		return 0, err
+	}
+	total = uint64(len(docs))
+
+	sortDocuments(docs, sort)
+	if offset > total {
+		offset = total
+	}
+	docs = docs[offset:]
+	if limit > 0 && limit < uint64(len(docs)) {
+		docs = docs[:limit]
+	}
+
+	return total, decodeDocuments(collection, docs, dest)
+}
+
+// Count returns the number of documents matching filter.
+func (s *MemoryStorage) Count(ctx context.Context, collection string, filter interface{}) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+
+	return uint64(len(docs)), err
+}
+
+// EstimatedCount returns the number of documents in the collection.
+func (s *MemoryStorage) EstimatedCount(ctx context.Context, collection string) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return uint64(len(s.collections[collection])), nil
+}
+
+// Distinct returns the unique values of field among documents matching filter, or an empty slice if none match.
+func (s *MemoryStorage) Distinct(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{}
+	for _, doc := range docs {
+		value, ok := lookup(doc, field)
+		if !ok {
+			continue
+		}
+
+		// like MongoDB, array values contribute each of their elements
+		candidates := []interface{}{value}
+		if elements, isArray := value.(bson.A); isArray {
+			candidates = elements
+		}
+
+	candidate:
+		for _, candidate := range candidates {
+			for _, known := range values {
+				if equal, err := equalValues(known, candidate); err != nil || equal {
+					if err != nil {
+						return nil, err
+					}
+
+					continue candidate
+				}
+			}
+			values = append(values, candidate)
+		}
+	}
+
+	return values, nil
+}
+
+// Exists reports whether a document matches filter.
+func (s *MemoryStorage) Exists(ctx context.Context, collection string, filter interface{}) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := s.find(collection, filter)
+
+	return len(docs) > 0, err
+}
+
+// RunInTransaction calls fn and restores the documents as they were before when it fails. Unlike a real
+// transaction, it isn't isolated from concurrent calls.
+func (s *MemoryStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...mongostorage.TxnOption) error {
+	s.mu.RLock()
+	snapshot := s.snapshot()
+	s.mu.RUnlock()
+
+	if err := fn(ctx); err != nil {
+		s.mu.Lock()
+		s.collections = snapshot
+		s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Insert makes insert into database, generating an ObjectID when the document has no _id.
+func (s *MemoryStorage) Insert(ctx context.Context, collection string, document interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.insert(collection, document)
+
+	return err
+}
+
+// InsertMany inserts documents into database, stopping at the first failure.
+func (s *MemoryStorage) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...mongostorage.InsertManyOption) (insertedIDs []interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, document := range documents {
+		id, err := s.insert(collection, document)
+		if err != nil {
+			return insertedIDs, err
+		}
+		insertedIDs = append(insertedIDs, id)
+	}
+
+	return insertedIDs, nil
+}
+
+// Update updates documents in the database. A zero docID is rejected with ErrInvalidID.
+func (s *MemoryStorage) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
+	if docID.IsZero() {
+		return 0, mongostorage.ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, modifiedCount, _, err = s.update(collection, bson.M{"_id": docID}, update, false, false)
+
+	return modifiedCount, err
+}
+
+// UpdateMany updates all documents matching filter in the database.
+func (s *MemoryStorage) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matchedCount, modifiedCount, _, err = s.update(collection, filter, update, true, false)
+
+	return matchedCount, modifiedCount, err
+}
+
+// FindOneAndUpdate atomically updates a single document and decodes it into destination, as it was after the update
+// when returnNew is set and before it otherwise. ErrNotFound is returned when nothing matched.
+func (s *MemoryStorage) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.first(collection, filter)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return notFound()
+	}
+
+	doc := s.collections[collection][index]
+	before := copyDocument(doc)
+	if err = applyUpdate(doc, update, false); err != nil {
+		s.collections[collection][index] = before
+
+		return err
+	}
+
+	if returnNew {
+		return decodeDocument(collection, doc, dest)
+	}
+
+	return decodeDocument(collection, before, dest)
+}
+
+// FindOneAndDelete atomically deletes the first document matching filter in sort order and decodes it into
+// destination. ErrNotFound is returned when nothing matched.
+func (s *MemoryStorage) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs, err := s.find(collection, filter)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return notFound()
+	}
+
+	sortDocuments(docs, sort)
+	if _, err = s.delete(collection, bson.M{"_id": docs[0]["_id"]}, false); err != nil {
+		return err
+	}
+
+	return decodeDocument(collection, docs[0], dest)
+}
+
+// FindOneAndReplace atomically replaces the first document matching filter, keeping its _id, and decodes it into
+// destination, as it was after the replacement when returnNew is set and before it otherwise. With upsert,
+// replacement is inserted when nothing matched. ErrNotFound is returned when there's no document to decode.
+func (s *MemoryStorage) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.first(collection, filter)
+	if err != nil {
+		return err
+	}
+
+	var before bson.M
+	if index >= 0 {
+		before = copyDocument(s.collections[collection][index])
+	} else if !upsert {
+		return notFound()
+	}
+
+	_, _, upsertedID, err := s.replace(collection, filter, replacement, upsert)
+	if err != nil {
+		return err
+	}
+
+	if !returnNew {
+		if before == nil {
+			return notFound()
+		}
+
+		return decodeDocument(collection, before, dest)
+	}
+
+	id := before["_id"]
+	if upsertedID != nil {
+		id = upsertedID
+	}
+	after, err := s.find(collection, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return decodeDocument(collection, after[0], dest)
+}
+
+// Upsert updates or inserts document in the database, docID being the filter like for the real storage.
+func (s *MemoryStorage) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _, upsertedID, err := s.update(collection, docID, update, false, true)
+	if err != nil || upsertedID == nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+// Replace replaces the whole document with docID by replacement, keeping its _id.
+func (s *MemoryStorage) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	if docID.IsZero() {
+		return 0, mongostorage.ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, modifiedCount, _, err = s.replace(collection, bson.M{"_id": docID}, replacement, false)
+
+	return modifiedCount, err
+}
+
+// ReplaceUpsert works like Replace, but inserts replacement with docID when there's no such document.
+func (s *MemoryStorage) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	if docID.IsZero() {
+		return 0, mongostorage.ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _, upsertedID, err := s.replace(collection, bson.M{"_id": docID}, replacement, true)
+	if err != nil || upsertedID == nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+// Delete deletes document in the database. A zero docID is rejected with ErrInvalidID.
+func (s *MemoryStorage) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
+	if docID.IsZero() {
+		return 0, mongostorage.ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(collection, bson.M{"_id": docID}, false)
+}
+
+// DeleteMany deletes filtered documents in the database.
+func (s *MemoryStorage) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(collection, filter, true)
+}
+
+// Truncate deletes every document of the collection.
+func (s *MemoryStorage) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deletedCount = int64(len(s.collections[collection]))
+	delete(s.collections, collection)
+
+	return deletedCount, nil
+}
+
+// BulkWrite executes the inserts, updates, replacements and deletes in order, stopping at the first failure.
+func (s *MemoryStorage) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (*mongo.BulkWriteResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}
+	for i, model := range models {
+		var matched, modified, deleted int64
+		var upsertedID interface{}
+		var err error
+
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			if _, err = s.insert(collection, m.Document); err == nil {
+				result.InsertedCount++
+			}
+		case *mongo.UpdateOneModel:
+			matched, modified, upsertedID, err = s.update(collection, m.Filter, m.Update, false, m.Upsert != nil && *m.Upsert)
+		case *mongo.UpdateManyModel:
+			matched, modified, upsertedID, err = s.update(collection, m.Filter, m.Update, true, m.Upsert != nil && *m.Upsert)
+		case *mongo.ReplaceOneModel:
+			matched, modified, upsertedID, err = s.replace(collection, m.Filter, m.Replacement, m.Upsert != nil && *m.Upsert)
+		case *mongo.DeleteOneModel:
+			deleted, err = s.delete(collection, m.Filter, false)
+		case *mongo.DeleteManyModel:
+			deleted, err = s.delete(collection, m.Filter, true)
+		default:
+			err = fmt.Errorf("memory storage doesn't support %T", model)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.MatchedCount += matched
+		result.ModifiedCount += modified
+		result.DeletedCount += deleted
+		if upsertedID != nil {
+			result.UpsertedCount++
+			result.UpsertedIDs[int64(i)] = upsertedID
+		}
+	}
+
+	return result, nil
+}
+
+// GetDatabaseName returns test database name
+func (s *MemoryStorage) GetDatabaseName() string {
+	return "test-database"
+}
+
+// Ping always succeeds
+func (s *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close always succeeds, the documents are kept
+func (s *MemoryStorage) Close(ctx context.Context) error {
+	return nil
+}
+
+// DropCollection removes every document of the collection
+func (s *MemoryStorage) DropCollection(ctx context.Context, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.collections, collection)
+
+	return nil
+}
+
+// find returns copies of the documents of the collection matching filter
+func (s *MemoryStorage) find(collection string, filter interface{}) ([]bson.M, error) {
+	filterDoc, err := toMap(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []bson.M{}
+	for _, doc := range s.collections[collection] {
+		ok, err := matches(doc, filterDoc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			docs = append(docs, copyDocument(doc))
+		}
+	}
+
+	return docs, nil
+}
+
+// first returns the index of the first document of the collection matching filter, or -1 if none does
+func (s *MemoryStorage) first(collection string, filter interface{}) (int, error) {
+	indexes, err := s.matching(collection, filter, false)
+	if err != nil || len(indexes) == 0 {
+		return -1, err
+	}
+
+	return indexes[0], nil
+}
+
+// matching returns the indexes of the documents of the collection matching filter, only the first one unless many
+func (s *MemoryStorage) matching(collection string, filter interface{}, many bool) ([]int, error) {
+	filterDoc, err := toMap(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for i, doc := range s.collections[collection] {
+		ok, err := matches(doc, filterDoc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			indexes = append(indexes, i)
+			if !many {
+				break
+			}
+		}
+	}
+
+	return indexes, nil
+}
+
+// insert stores a copy of the document, rejecting a duplicate _id like a unique index would
+func (s *MemoryStorage) insert(collection string, document interface{}) (interface{}, error) {
+	doc, err := toMap(document)
+	if err != nil {
+		return nil, err
+	}
+
+	id := newID(doc)
+	existing, err := s.first(collection, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if existing >= 0 {
+		return nil, mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+			Code:    11000,
+			Message: fmt.Sprintf("E11000 duplicate key error collection: %s dup key: { _id: %v }", collection, id),
+		}}}
+	}
+
+	s.collections[collection] = append(s.collections[collection], doc)
+
+	return id, nil
+}
+
+// update applies update to the first or every document matching filter, inserting one when nothing matched
+// and upsert is set
+func (s *MemoryStorage) update(collection string, filter, update interface{}, many, upsert bool) (matched, modified int64, upsertedID interface{}, err error) {
+	indexes, err := s.matching(collection, filter, many)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if len(indexes) == 0 && upsert {
+		filterDoc, err := toMap(filter)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		doc := equalityFields(filterDoc)
+		if err = applyUpdate(doc, update, true); err != nil {
+			return 0, 0, nil, err
+		}
+		upsertedID, err = s.insert(collection, doc)
+
+		return 0, 0, upsertedID, err
+	}
+
+	docs := s.collections[collection]
+	for _, index := range indexes {
+		before := copyDocument(docs[index])
+		if err = applyUpdate(docs[index], update, false); err != nil {
+			docs[index] = before
+
+			return matched, modified, nil, err
+		}
+
+		matched++
+		if !reflect.DeepEqual(before, docs[index]) {
+			modified++
+		}
+	}
+
+	return matched, modified, nil, nil
+}
+
+// replace replaces the first document matching filter, keeping its _id, inserting it when nothing matched and
+// upsert is set
+func (s *MemoryStorage) replace(collection string, filter, replacement interface{}, upsert bool) (matched, modified int64, upsertedID interface{}, err error) {
+	doc, err := toMap(replacement)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	index, err := s.first(collection, filter)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if index < 0 {
+		if !upsert {
+			return 0, 0, nil, nil
+		}
+
+		filterDoc, err := toMap(filter)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if id, ok := filterDoc["_id"]; ok {
+			doc["_id"] = id
+		}
+		upsertedID, err = s.insert(collection, doc)
+
+		return 0, 0, upsertedID, err
+	}
+
+	existing := s.collections[collection][index]
+	doc["_id"] = existing["_id"]
+	s.collections[collection][index] = doc
+	if reflect.DeepEqual(existing, doc) {
+		return 1, 0, nil, nil
+	}
+
+	return 1, 1, nil, nil
+}
+
+// delete removes the first or every document matching filter
+func (s *MemoryStorage) delete(collection string, filter interface{}, many bool) (int64, error) {
+	indexes, err := s.matching(collection, filter, many)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := map[int]bool{}
+	for _, index := range indexes {
+		deleted[index] = true
+	}
+
+	kept := []bson.M{}
+	for i, doc := range s.collections[collection] {
+		if !deleted[i] {
+			kept = append(kept, doc)
+		}
+	}
+	s.collections[collection] = kept
+
+	return int64(len(indexes)), nil
+}
+
+// snapshot returns a deep copy of all collections
+func (s *MemoryStorage) snapshot() map[string][]bson.M {
+	collections := make(map[string][]bson.M, len(s.collections))
+	for name, docs := range s.collections {
+		copied := make([]bson.M, 0, len(docs))
+		for _, doc := range docs {
+			copied = append(copied, copyDocument(doc))
+		}
+		collections[name] = copied
+	}
+
+	return collections
+}
+
+// notFound returns the error of the real storage for a missing document
+func notFound() error {
+	return fmt.Errorf("%w: %w", mongostorage.ErrNotFound, mongo.ErrNoDocuments)
+}
@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUser struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Name   string             `bson:"name"`
+	Age    int64              `bson:"age"`
+	Status string             `bson:"status,omitempty"`
+}
+
+func TestMemoryStorageInsertAndFindOne(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := memoryUser{ID: primitive.NewObjectID(), Name: "ann", Age: 30}
+
+	require.NoError(t, storage.Insert(ctx, "users", user))
+
+	var found memoryUser
+	require.NoError(t, storage.FindOne(ctx, "users", bson.M{"name": "ann"}, &found))
+	assert.Equal(t, user, found)
+
+	err := storage.FindOne(ctx, "users", bson.M{"name": "bob"}, &found)
+	assert.ErrorIs(t, err, mongostorage.ErrNotFound)
+}
+
+func TestMemoryStorageUpdate(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := memoryUser{ID: primitive.NewObjectID(), Name: "ann", Age: 30}
+	require.NoError(t, storage.Insert(ctx, "users", user))
+
+	modified, err := storage.Update(ctx, "users", user.ID, bson.M{
+		"$set": bson.M{"status": "active"},
+		"$inc": bson.M{"age": 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	var found memoryUser
+	require.NoError(t, storage.FindOne(ctx, "users", bson.M{"_id": user.ID}, &found))
+	assert.Equal(t, "active", found.Status)
+	assert.Equal(t, int64(31), found.Age)
+}
+
+func TestMemoryStorageIncrementKeepsIntegerPrecision(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	id := primitive.NewObjectID()
+	require.NoError(t, storage.Insert(ctx, "counters", bson.M{"_id": id, "count": int64(math.MaxInt64 - 1), "ratio": 0.5}))
+
+	_, err := storage.Update(ctx, "counters", id, bson.M{"$inc": bson.M{"count": int32(1), "ratio": int32(1)}})
+	require.NoError(t, err)
+
+	var found bson.M
+	require.NoError(t, storage.FindOne(ctx, "counters", bson.M{"_id": id}, &found))
+	assert.Equal(t, int64(math.MaxInt64), found["count"])
+	assert.Equal(t, 1.5, found["ratio"])
+}
+
+func TestMemoryStorageUpsertInserts(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	upserted, err := storage.Upsert(ctx, "users", bson.M{"name": "ann"}, bson.M{
+		"$set":         bson.M{"status": "active"},
+		"$setOnInsert": bson.M{"age": int64(30)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), upserted)
+
+	var found memoryUser
+	require.NoError(t, storage.FindOne(ctx, "users", bson.M{"name": "ann"}, &found))
+	assert.False(t, found.ID.IsZero())
+	assert.Equal(t, "active", found.Status)
+	assert.Equal(t, int64(30), found.Age)
+
+	// $setOnInsert is ignored once the document exists
+	upserted, err = storage.Upsert(ctx, "users", bson.M{"name": "ann"}, bson.M{"$setOnInsert": bson.M{"age": int64(40)}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), upserted)
+	require.NoError(t, storage.FindOne(ctx, "users", bson.M{"name": "ann"}, &found))
+	assert.Equal(t, int64(30), found.Age)
+}
+
+func TestMemoryStorageDelete(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := memoryUser{ID: primitive.NewObjectID(), Name: "ann"}
+	require.NoError(t, storage.Insert(ctx, "users", user))
+
+	deleted, err := storage.Delete(ctx, "users", user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	exists, err := storage.Exists(ctx, "users", bson.M{"_id": user.ID})
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMemoryStorageFindManySorted(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	for _, user := range []memoryUser{
+		{ID: primitive.NewObjectID(), Name: "bob", Age: 25},
+		{ID: primitive.NewObjectID(), Name: "ann", Age: 30},
+		{ID: primitive.NewObjectID(), Name: "cid", Age: 20},
+	} {
+		require.NoError(t, storage.Insert(ctx, "users", user))
+	}
+
+	var users []memoryUser
+	total, err := storage.FindMany(ctx, "users", nil, 2, 0, "-age", &users)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), total)
+	require.Len(t, users, 2)
+	assert.Equal(t, "ann", users[0].Name)
+	assert.Equal(t, "bob", users[1].Name)
+}
+
+func TestMemoryStorageUnsupportedOperator(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := memoryUser{ID: primitive.NewObjectID(), Name: "ann"}
+	require.NoError(t, storage.Insert(ctx, "users", user))
+
+	_, err := storage.Update(ctx, "users", user.ID, bson.M{"$push": bson.M{"tags": "new"}})
+	assert.ErrorContains(t, err, "$push")
+}
+
+func TestMemoryStorageRunInTransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+	user := memoryUser{ID: primitive.NewObjectID(), Name: "ann"}
+	require.NoError(t, storage.Insert(ctx, "users", user))
+
+	failure := errors.New("failure")
+	err := storage.RunInTransaction(ctx, func(ctx context.Context) error {
+		if _, err := storage.Delete(ctx, "users", user.ID); err != nil {
+			return err
+		}
+		if err := storage.Insert(ctx, "users", memoryUser{ID: primitive.NewObjectID(), Name: "bob"}); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	var users []memoryUser
+	require.NoError(t, storage.FindAll(ctx, "users", nil, &users))
+	assert.Equal(t, []memoryUser{user}, users)
+}
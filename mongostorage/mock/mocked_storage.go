@@ -2,17 +2,21 @@ package mock
 
 import (
 	"context"
+	"sync"
 	"testing"
 
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// MockedStorageReader is a mock for StorageReader interface
+// MockedStorageReader is a mock for StorageReader interface.
+// A method whose mock func isn't set returns zero values and leaves the destination untouched.
 type MockedStorageReader struct {
-	FindMock     func(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error)
-	FindAllMock  func(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error)
+	FindMock     func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error)
+	FindAllMock  func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error)
 	FindManyMock func(
 		ctx context.Context,
 		collection string,
@@ -20,80 +24,400 @@ type MockedStorageReader struct {
 		limit, offset uint64,
 		sort string,
 		dest interface{},
+		opts ...mongostorage.FindOption,
 	) (total uint64, err error)
+	CountMock          func(ctx context.Context, collection string, filter interface{}) (uint64, error)
+	EstimatedCountMock func(ctx context.Context, collection string) (uint64, error)
+	DistinctMock       func(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error)
+	ExistsMock         func(ctx context.Context, collection string, filter interface{}) (bool, error)
+	// RespectContext makes every method return the error of an already done ctx without calling the mock func,
+	// like the real storage does
+	RespectContext bool
+}
+
+// contextErr returns the error of ctx when RespectContext is set
+func (mock *MockedStorageReader) contextErr(ctx context.Context) error {
+	if !mock.RespectContext {
+		return nil
+	}
+
+	return ctx.Err()
 }
 
 // FindOne returns a row into destination.
-func (mock *MockedStorageReader) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	return mock.FindMock(ctx, collection, filter, dest)
+func (mock *MockedStorageReader) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return err
+	}
+
+	if mock.FindMock == nil {
+		return nil
+	}
+
+	return mock.FindMock(ctx, collection, filter, dest, opts...)
 }
 
 // FindAll returns rows into destination.
-func (mock *MockedStorageReader) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	return mock.FindAllMock(ctx, collection, filter, dest)
+func (mock *MockedStorageReader) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return err
+	}
+
+	if mock.FindAllMock == nil {
+		return nil
+	}
+
+	return mock.FindAllMock(ctx, collection, filter, dest, opts...)
 }
 
 // FindMany returns rows into destination.
-func (mock *MockedStorageReader) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}) (total uint64, err error) {
-	return mock.FindManyMock(ctx, collection, filter, limit, offset, sort, dest)
+func (mock *MockedStorageReader) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...mongostorage.FindOption) (total uint64, err error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return 0, err
+	}
+
+	if mock.FindManyMock == nil {
+		return 0, nil
+	}
+
+	return mock.FindManyMock(ctx, collection, filter, limit, offset, sort, dest, opts...)
+}
+
+// Count returns the number of documents matching filter.
+func (mock *MockedStorageReader) Count(ctx context.Context, collection string, filter interface{}) (uint64, error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return 0, err
+	}
+
+	if mock.CountMock == nil {
+		return 0, nil
+	}
+
+	return mock.CountMock(ctx, collection, filter)
+}
+
+// EstimatedCount returns the approximate number of documents in the collection.
+func (mock *MockedStorageReader) EstimatedCount(ctx context.Context, collection string) (uint64, error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return 0, err
+	}
+
+	if mock.EstimatedCountMock == nil {
+		return 0, nil
+	}
+
+	return mock.EstimatedCountMock(ctx, collection)
+}
+
+// Distinct returns the unique values of field among documents matching filter.
+func (mock *MockedStorageReader) Distinct(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	if mock.DistinctMock == nil {
+		return nil, nil
+	}
+
+	return mock.DistinctMock(ctx, collection, field, filter)
+}
+
+// Exists reports whether a document matches filter.
+func (mock *MockedStorageReader) Exists(ctx context.Context, collection string, filter interface{}) (bool, error) {
+	if err := mock.contextErr(ctx); err != nil {
+		return false, err
+	}
+
+	if mock.ExistsMock == nil {
+		return false, nil
+	}
+
+	return mock.ExistsMock(ctx, collection, filter)
 }
 
 // NewStorageReaderStub will return a stub for StorageReader that will return given result
 func NewStorageReaderStub(t *testing.T, result string) *MockedStorageReader {
-	return &MockedStorageReader{FindAllMock: func(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
+	return &MockedStorageReader{FindAllMock: func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) (err error) {
 		assert.NoError(t, bson.UnmarshalExtJSON([]byte(result), true, dest))
 
 		return nil
 	}}
 }
 
-// MockedStorageWriter is a mock for StorageWriter interface
+// Call is a recorded call of a MockedStorageWriter method
+type Call struct {
+	Operation  string
+	Collection string
+	Args       []interface{}
+}
+
+// MockedStorageWriter is a mock for StorageWriter interface.
+// Every call is recorded, so a mock func only needs to be set to return something other than zero values: a method
+// whose mock func isn't set returns zero values, except RunInTransaction which calls fn.
 type MockedStorageWriter struct {
-	RunInTransactionMock func(ctx context.Context, fn func(context.Context) error) error
-	InsertMock           func(ctx context.Context, collection string, document interface{}) error
-	UpdateMock           func(ctx context.Context, collection string, docID interface{}, update interface{}) (modifiedCount int64, err error)
-	UpsertMock           func(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error)
-	DeleteMock           func(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error)
-	DeleteManyMock       func(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error)
+	// mu guards Calls; it's held by pointer, so the mock can be copied
+	mu *sync.Mutex
+	// Calls lists the calls in the order they were made, arguments except ctx and destinations included
+	Calls []Call
+
+	RunInTransactionMock  func(ctx context.Context, fn func(context.Context) error, opts ...mongostorage.TxnOption) error
+	InsertMock            func(ctx context.Context, collection string, document interface{}) error
+	InsertManyMock        func(ctx context.Context, collection string, documents []interface{}, opts ...mongostorage.InsertManyOption) (insertedIDs []interface{}, err error)
+	UpdateMock            func(ctx context.Context, collection string, docID interface{}, update interface{}) (modifiedCount int64, err error)
+	UpdateManyMock        func(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error)
+	FindOneAndUpdateMock  func(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error
+	FindOneAndDeleteMock  func(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error
+	FindOneAndReplaceMock func(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error
+	UpsertMock            func(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error)
+	ReplaceMock           func(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error)
+	ReplaceUpsertMock     func(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error)
+	DeleteMock            func(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error)
+	DeleteManyMock        func(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error)
+	TruncateMock          func(ctx context.Context, collection string) (deletedCount int64, err error)
+	BulkWriteMock         func(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (*mongo.BulkWriteResult, error)
 }
 
 // RunInTransaction encapsulates the function that needs to run in a transaction.
-func (mock *MockedStorageWriter) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
-	return mock.RunInTransactionMock(ctx, fn)
+// Without RunInTransactionMock, fn is called with ctx.
+func (mock *MockedStorageWriter) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...mongostorage.TxnOption) error {
+	mock.record("RunInTransaction", "")
+	if mock.RunInTransactionMock == nil {
+		return fn(ctx)
+	}
+
+	return mock.RunInTransactionMock(ctx, fn, opts...)
 }
 
 // Insert makes insert into database.
 func (mock *MockedStorageWriter) Insert(ctx context.Context, collection string, document interface{}) error {
+	mock.record("Insert", collection, document)
+	if mock.InsertMock == nil {
+		return nil
+	}
+
 	return mock.InsertMock(ctx, collection, document)
 }
 
+// InsertMany inserts documents into database in a single round trip.
+func (mock *MockedStorageWriter) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...mongostorage.InsertManyOption) (insertedIDs []interface{}, err error) {
+	mock.record("InsertMany", collection, documents)
+	if mock.InsertManyMock == nil {
+		return nil, nil
+	}
+
+	return mock.InsertManyMock(ctx, collection, documents, opts...)
+}
+
 // Update updates documents in the database.
 func (mock *MockedStorageWriter) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
+	mock.record("Update", collection, docID, update)
+	if mock.UpdateMock == nil {
+		return 0, nil
+	}
+
 	return mock.UpdateMock(ctx, collection, docID, update)
 }
 
+// UpdateMany updates all documents matching filter in the database.
+func (mock *MockedStorageWriter) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	mock.record("UpdateMany", collection, filter, update)
+	if mock.UpdateManyMock == nil {
+		return 0, 0, nil
+	}
+
+	return mock.UpdateManyMock(ctx, collection, filter, update)
+}
+
+// FindOneAndUpdate atomically updates a single document and decodes it into destination.
+func (mock *MockedStorageWriter) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error {
+	mock.record("FindOneAndUpdate", collection, filter, update, returnNew)
+	if mock.FindOneAndUpdateMock == nil {
+		return nil
+	}
+
+	return mock.FindOneAndUpdateMock(ctx, collection, filter, update, dest, returnNew)
+}
+
+// FindOneAndDelete atomically deletes a single document and decodes it into destination.
+func (mock *MockedStorageWriter) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error {
+	mock.record("FindOneAndDelete", collection, filter, sort)
+	if mock.FindOneAndDeleteMock == nil {
+		return nil
+	}
+
+	return mock.FindOneAndDeleteMock(ctx, collection, filter, sort, dest)
+}
+
+// FindOneAndReplace atomically replaces a single document and decodes it into destination.
+func (mock *MockedStorageWriter) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error {
+	mock.record("FindOneAndReplace", collection, filter, replacement, returnNew, upsert)
+	if mock.FindOneAndReplaceMock == nil {
+		return nil
+	}
+
+	return mock.FindOneAndReplaceMock(ctx, collection, filter, replacement, returnNew, upsert, dest)
+}
+
 // Upsert updates or inserts document in the database.
 func (mock *MockedStorageWriter) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
+	mock.record("Upsert", collection, docID, update)
+	if mock.UpsertMock == nil {
+		return 0, nil
+	}
+
 	return mock.UpsertMock(ctx, collection, docID, update)
 }
 
+// Replace replaces the whole document in the database.
+func (mock *MockedStorageWriter) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	mock.record("Replace", collection, docID, replacement)
+	if mock.ReplaceMock == nil {
+		return 0, nil
+	}
+
+	return mock.ReplaceMock(ctx, collection, docID, replacement)
+}
+
+// ReplaceUpsert replaces or inserts the whole document in the database.
+func (mock *MockedStorageWriter) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	mock.record("ReplaceUpsert", collection, docID, replacement)
+	if mock.ReplaceUpsertMock == nil {
+		return 0, nil
+	}
+
+	return mock.ReplaceUpsertMock(ctx, collection, docID, replacement)
+}
+
 // Delete deletes document in the database.
 func (mock *MockedStorageWriter) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
+	mock.record("Delete", collection, docID)
+	if mock.DeleteMock == nil {
+		return 0, nil
+	}
+
 	return mock.DeleteMock(ctx, collection, docID)
 }
 
 // DeleteMany deletes filtered documents in the database.
 func (mock *MockedStorageWriter) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
+	mock.record("DeleteMany", collection, filter)
+	if mock.DeleteManyMock == nil {
+		return 0, nil
+	}
+
 	return mock.DeleteManyMock(ctx, collection, filter)
 }
 
+// Truncate deletes every document of the collection.
+func (mock *MockedStorageWriter) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	mock.record("Truncate", collection)
+	if mock.TruncateMock == nil {
+		return 0, nil
+	}
+
+	return mock.TruncateMock(ctx, collection)
+}
+
+// BulkWrite executes a batch of inserts, updates and deletes in a single command.
+func (mock *MockedStorageWriter) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (*mongo.BulkWriteResult, error) {
+	mock.record("BulkWrite", collection, models, ordered)
+	if mock.BulkWriteMock == nil {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	return mock.BulkWriteMock(ctx, collection, models, ordered)
+}
+
+// initMu guards the lazy creation of the mutexes of MockedStorageWriter
+var initMu sync.Mutex
+
+// lock locks the mutex guarding Calls, creating it on first use so the zero value of the mock is ready to use
+func (mock *MockedStorageWriter) lock() *sync.Mutex {
+	initMu.Lock()
+	if mock.mu == nil {
+		mock.mu = &sync.Mutex{}
+	}
+	mu := mock.mu
+	initMu.Unlock()
+
+	mu.Lock()
+
+	return mu
+}
+
+// record appends the call to Calls
+func (mock *MockedStorageWriter) record(operation, collection string, args ...interface{}) {
+	mu := mock.lock()
+	defer mu.Unlock()
+
+	mock.Calls = append(mock.Calls, Call{Operation: operation, Collection: collection, Args: args})
+}
+
+// CallsTo returns the recorded calls of operation on the collection
+func (mock *MockedStorageWriter) CallsTo(operation, collection string) []Call {
+	mu := mock.lock()
+	defer mu.Unlock()
+
+	var calls []Call
+	for _, call := range mock.Calls {
+		if call.Operation == operation && call.Collection == collection {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// AssertCalled asserts operation was called on the collection at least once
+func (mock *MockedStorageWriter) AssertCalled(t *testing.T, operation, collection string) bool {
+	return assert.NotEmpty(t, mock.CallsTo(operation, collection), "%s(%s) was not called", operation, collection)
+}
+
+// AssertCalledTimes asserts operation was called on the collection exactly times times
+func (mock *MockedStorageWriter) AssertCalledTimes(t *testing.T, operation, collection string, times int) bool {
+	return assert.Len(t, mock.CallsTo(operation, collection), times, "unexpected number of %s(%s) calls", operation, collection)
+}
+
+var _ mongostorage.StorageReaderWriter = (*MockedStorageReaderWriter)(nil)
+
 // MockedStorageReaderWriter is mock for StorageReaderWriter interface
 type MockedStorageReaderWriter struct {
 	MockedStorageReader
 	MockedStorageWriter
+	PingMock           func(ctx context.Context) error
+	CloseMock          func(ctx context.Context) error
+	DropCollectionMock func(ctx context.Context, collection string) error
 }
 
 // GetDatabaseName returns test database name
-func (mock MockedStorageReaderWriter) GetDatabaseName() string {
+func (mock *MockedStorageReaderWriter) GetDatabaseName() string {
 	return "test-database"
 }
+
+// Ping returns the result of PingMock, or nil when it isn't set
+func (mock *MockedStorageReaderWriter) Ping(ctx context.Context) error {
+	if mock.PingMock == nil {
+		return nil
+	}
+
+	return mock.PingMock(ctx)
+}
+
+// Close returns the result of CloseMock, or nil when it isn't set
+func (mock *MockedStorageReaderWriter) Close(ctx context.Context) error {
+	if mock.CloseMock == nil {
+		return nil
+	}
+
+	return mock.CloseMock(ctx)
+}
+
+// DropCollection returns the result of DropCollectionMock, or nil when it isn't set
+func (mock *MockedStorageReaderWriter) DropCollection(ctx context.Context, collection string) error {
+	mock.record("DropCollection", collection)
+	if mock.DropCollectionMock == nil {
+		return nil
+	}
+
+	return mock.DropCollectionMock(ctx, collection)
+}
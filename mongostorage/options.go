@@ -0,0 +1,57 @@
+package mongostorage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Option configures the storage created by New.
+type Option func(*Storage)
+
+// defaultTransactionAttempts is how many times RunInTransaction runs a transaction failing with a transient error.
+const defaultTransactionAttempts = 3
+
+// WithMaxDocumentDepth rejects documents nested deeper than depth with ErrDocumentTooDeep before they're written by
+// Insert, InsertIdempotent, InsertMany, Replace, ReplaceUpsert, FindOneAndReplace, BulkWrite or UpsertMany, and the
+// $set and $setOnInsert documents of Update, Upsert and the updates of BulkWrite. A top-level document has depth 1,
+// and every embedded document or array adds one level. Unlimited by default.
+func WithMaxDocumentDepth(depth int) Option {
+	return func(s *Storage) {
+		if depth > 0 {
+			s.maxDocumentDepth = depth
+		}
+	}
+}
+
+// WithTransactionAttempts sets how many times RunInTransaction runs the transaction function when MongoDB reports
+// a TransientTransactionError, and how many times it commits on UnknownTransactionCommitResult. Defaults to 3.
+func WithTransactionAttempts(attempts int) Option {
+	return func(s *Storage) {
+		if attempts > 0 {
+			s.transactionAttempts = attempts
+		}
+	}
+}
+
+// WithOperationTimeout bounds every operation of the StorageReaderWriter interface, such as FindOne or Update, to d,
+// and so are the Storage helpers running a single query or command, such as FindByIDs, FindPage, IncrementMany or
+// CreateIndex. The deadline of the context passed in is kept when it's earlier. RunInTransaction itself isn't
+// bounded, only the operations run within it, and neither are streaming or batched operations, whose duration
+// depends on the data or the caller: Watch, ForEach, ForEachBatch, DeleteManyBatched, DeleteManyThrottled,
+// TwoPhaseCommit, RecoverTwoPhaseCommits, ExportCollectionJSON, ExportStable, CollectionChecksum and the GridFS
+// transfers. Admin commands aren't bounded either. Unbounded by default.
+func WithOperationTimeout(d time.Duration) Option {
+	return func(s *Storage) {
+		s.operationTimeout = d
+	}
+}
+
+// WithWriteConcern sets the write concern of every write, e.g. writeconcern.Majority() for writes that must survive
+// a failover. Writes use the write concern of the client by default. Writes within RunInTransaction use the write
+// concern of the transaction instead.
+func WithWriteConcern(wc *writeconcern.WriteConcern) Option {
+	return func(s *Storage) {
+		s.writeConcern = wc
+	}
+}
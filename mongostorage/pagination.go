@@ -0,0 +1,209 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindPage returns up to limit rows that come after the document with afterID in sort order into destination,
+// without skipping over the previous pages. A zero afterID starts from the first page. It returns the _id of the last
+// returned document, to be passed as afterID for the next page, or a zero ObjectID when there are no more rows.
+//
+// The sort uses the FindMany syntax and is always completed with _id, so pages stay stable even when the sort keys
+// aren't unique: documents sharing the sort values are ordered by _id. Documents must have ObjectID identifiers and
+// should carry every sort key, as missing keys compare as null.
+func (s *Storage) FindPage(
+	ctx context.Context,
+	collection string,
+	filter interface{},
+	afterID primitive.ObjectID,
+	limit uint64,
+	sort string,
+	dest interface{},
+) (nextCursor primitive.ObjectID, err error) {
+	defer wrapError(&err, "FindPage", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	sortDoc := keysetSort(sort)
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if !afterID.IsZero() {
+		after, err := s.keysetFilter(ctx, collection, afterID, sortDoc)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		filter = bson.M{"$and": bson.A{filter, after}}
+	}
+
+	findOptions := options.Find().SetSort(sortDoc).SetLimit(int64(limit))
+	cursor, err := s.database.Collection(collection).Find(ctx, filter, findOptions)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	var last bson.Raw
+	if err = decodeCursor(ctx, collection, cursor, dest, func(raw bson.Raw) { last = raw }); err != nil {
+		return primitive.NilObjectID, err
+	}
+	if last == nil {
+		return primitive.NilObjectID, nil
+	}
+
+	lastID, ok := last.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("document _id %s is not an ObjectID", last.Lookup("_id"))
+	}
+
+	return lastID, nil
+}
+
+// keysetSort parses the sort and makes _id its last key, so it totally orders documents.
+func keysetSort(sort string) bson.D {
+	sortDoc := parseSort(sort)
+	for i, elem := range sortDoc {
+		if elem.Key == "_id" {
+			// keys after a unique one never decide the order
+			return sortDoc[:i+1]
+		}
+	}
+
+	return append(sortDoc, bson.E{Key: "_id", Value: 1})
+}
+
+// keysetFilter matches documents that come after the document with afterID in sortDoc order.
+func (s *Storage) keysetFilter(ctx context.Context, collection string, afterID primitive.ObjectID, sortDoc bson.D) (bson.M, error) {
+	projection := bson.M{}
+	for _, elem := range sortDoc {
+		projection[elem.Key] = 1
+	}
+
+	cursorDoc, err := s.database.Collection(collection).
+		FindOne(ctx, bson.M{"_id": afterID}, options.FindOne().SetProjection(projection)).
+		Raw()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("page cursor %s not found: %w", afterID.Hex(), err)
+		}
+
+		return nil, err
+	}
+
+	// (k1 > v1) or (k1 = v1 and k2 > v2) or ..., with < for descending keys
+	clauses := bson.A{}
+	for i, elem := range sortDoc {
+		clause := bson.D{}
+		for _, prev := range sortDoc[:i] {
+			clause = append(clause, bson.E{Key: prev.Key, Value: lookupPath(cursorDoc, prev.Key)})
+		}
+
+		operator := "$gt"
+		if elem.Value == -1 {
+			operator = "$lt"
+		}
+		clause = append(clause, bson.E{Key: elem.Key, Value: bson.M{operator: lookupPath(cursorDoc, elem.Key)}})
+		clauses = append(clauses, clause)
+	}
+
+	return bson.M{"$or": clauses}, nil
+}
+
+// lookupPath returns the value at a dotted path of the document, or null when it's missing.
+func lookupPath(doc bson.Raw, path string) interface{} {
+	value, err := doc.LookupErr(strings.Split(path, ".")...)
+	if err != nil {
+		return nil
+	}
+
+	return value
+}
+
+// facetedTotal is the total output by the FindManyFaceted pipeline, next to the documents.
+type facetedTotal []struct {
+	Count uint64 `bson:"count"`
+}
+
+// facetedPageType returns the type of the single document returned by the FindManyFaceted pipeline, with the
+// documents decoded into sliceType, so they're decoded by the cursor and thus with the database registry.
+func facetedPageType(sliceType reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Documents", Type: sliceType, Tag: `bson:"documents"`},
+		{Name: "Total", Type: reflect.TypeOf(facetedTotal{}), Tag: `bson:"total"`},
+	})
+}
+
+// FindManyFaceted works like FindMany, but fetches the page of rows and the total count in a single $facet aggregation,
+// so both come from the same snapshot of the collection. A zero limit returns all rows after offset.
+//
+// The whole page is returned as one document, so it must fit within the 16MB document limit.
+func (s *Storage) FindManyFaceted(
+	ctx context.Context,
+	collection string,
+	filter interface{},
+	limit, offset uint64,
+	sort string,
+	dest interface{},
+) (total uint64, err error) {
+	defer wrapError(&err, "FindManyFaceted", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return 0, err
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	page := bson.A{}
+	if sort != "" {
+		page = append(page, bson.M{"$sort": parseSort(sort)})
+	}
+	page = append(page, bson.M{"$skip": int64(offset)})
+	if limit > 0 {
+		page = append(page, bson.M{"$limit": int64(limit)})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: bson.M{
+			"documents": page,
+			"total":     bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+
+	cursor, err := s.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	// $facet always outputs one document, and $count outputs nothing when no document matched
+	if !cursor.Next(ctx) {
+		return 0, cursor.Err()
+	}
+	result := reflect.New(facetedPageType(reflect.TypeOf(dest).Elem()))
+	if err = cursor.Decode(result.Interface()); err != nil {
+		return 0, decodeError(err, collection, dest)
+	}
+
+	reflect.ValueOf(dest).Elem().Set(result.Elem().Field(0))
+	if counts := result.Elem().Field(1).Interface().(facetedTotal); len(counts) > 0 {
+		total = counts[0].Count
+	}
+
+	return total, nil
+}
@@ -0,0 +1,151 @@
+package mongostorage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ StorageReaderWriter = (*ReadOnlyStorage)(nil)
+
+// ReadOnlyStorage wraps StorageReaderWriter and rejects every write with ErrReadOnly without touching the database
+type ReadOnlyStorage struct {
+	upstream StorageReaderWriter
+}
+
+// NewReadOnly creates new mongostorage that only allows read operations
+func NewReadOnly(upstream StorageReaderWriter) *ReadOnlyStorage {
+	return &ReadOnlyStorage{upstream: upstream}
+}
+
+// FindOne returns a row into destination.
+func (s *ReadOnlyStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.upstream.FindOne(ctx, collection, filter, dest, opts...)
+}
+
+// FindAll returns all rows matching filter into destination.
+func (s *ReadOnlyStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.upstream.FindAll(ctx, collection, filter, dest, opts...)
+}
+
+// FindMany returns rows into destination.
+func (s *ReadOnlyStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...FindOption) (total uint64, err error) {
+	return s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, dest, opts...)
+}
+
+// Count returns the number of documents matching filter.
+func (s *ReadOnlyStorage) Count(ctx context.Context, collection string, filter interface{}) (uint64, error) {
+	return s.upstream.Count(ctx, collection, filter)
+}
+
+// EstimatedCount returns the approximate number of documents in the collection.
+func (s *ReadOnlyStorage) EstimatedCount(ctx context.Context, collection string) (uint64, error) {
+	return s.upstream.EstimatedCount(ctx, collection)
+}
+
+// Distinct returns the unique values of field among documents matching filter.
+func (s *ReadOnlyStorage) Distinct(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error) {
+	return s.upstream.Distinct(ctx, collection, field, filter)
+}
+
+// Exists reports whether a document matches filter.
+func (s *ReadOnlyStorage) Exists(ctx context.Context, collection string, filter interface{}) (bool, error) {
+	return s.upstream.Exists(ctx, collection, filter)
+}
+
+// RunInTransaction encapsulates the function that needs to run in a transaction.
+// Reads inside the transaction are allowed, writes made through this storage are still rejected.
+func (s *ReadOnlyStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...TxnOption) error {
+	return s.upstream.RunInTransaction(ctx, fn, opts...)
+}
+
+// Insert returns ErrReadOnly.
+func (s *ReadOnlyStorage) Insert(context.Context, string, interface{}) error {
+	return ErrReadOnly
+}
+
+// InsertMany returns ErrReadOnly.
+func (s *ReadOnlyStorage) InsertMany(context.Context, string, []interface{}, ...InsertManyOption) (insertedIDs []interface{}, err error) {
+	return nil, ErrReadOnly
+}
+
+// Update returns ErrReadOnly.
+func (s *ReadOnlyStorage) Update(context.Context, string, primitive.ObjectID, interface{}) (modifiedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// UpdateMany returns ErrReadOnly.
+func (s *ReadOnlyStorage) UpdateMany(context.Context, string, interface{}, interface{}) (matchedCount, modifiedCount int64, err error) {
+	return 0, 0, ErrReadOnly
+}
+
+// FindOneAndUpdate returns ErrReadOnly.
+func (s *ReadOnlyStorage) FindOneAndUpdate(context.Context, string, interface{}, interface{}, interface{}, bool) error {
+	return ErrReadOnly
+}
+
+// FindOneAndDelete returns ErrReadOnly.
+func (s *ReadOnlyStorage) FindOneAndDelete(context.Context, string, interface{}, string, interface{}) error {
+	return ErrReadOnly
+}
+
+// FindOneAndReplace returns ErrReadOnly.
+func (s *ReadOnlyStorage) FindOneAndReplace(context.Context, string, interface{}, interface{}, bool, bool, interface{}) error {
+	return ErrReadOnly
+}
+
+// Upsert returns ErrReadOnly.
+func (s *ReadOnlyStorage) Upsert(context.Context, string, interface{}, interface{}) (upsertedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// Replace returns ErrReadOnly.
+func (s *ReadOnlyStorage) Replace(context.Context, string, primitive.ObjectID, interface{}) (modifiedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// ReplaceUpsert returns ErrReadOnly.
+func (s *ReadOnlyStorage) ReplaceUpsert(context.Context, string, primitive.ObjectID, interface{}) (upsertedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// Delete returns ErrReadOnly.
+func (s *ReadOnlyStorage) Delete(context.Context, string, primitive.ObjectID) (deletedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// DeleteMany returns ErrReadOnly.
+func (s *ReadOnlyStorage) DeleteMany(context.Context, string, interface{}) (deletedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// Truncate returns ErrReadOnly.
+func (s *ReadOnlyStorage) Truncate(context.Context, string) (deletedCount int64, err error) {
+	return 0, ErrReadOnly
+}
+
+// BulkWrite returns ErrReadOnly.
+func (s *ReadOnlyStorage) BulkWrite(context.Context, string, []mongo.WriteModel, bool) (*mongo.BulkWriteResult, error) {
+	return nil, ErrReadOnly
+}
+
+// GetDatabaseName returns the name of the current database.
+func (s *ReadOnlyStorage) GetDatabaseName() string {
+	return s.upstream.GetDatabaseName()
+}
+
+// Ping verifies the connection to the database is alive.
+func (s *ReadOnlyStorage) Ping(ctx context.Context) error {
+	return s.upstream.Ping(ctx)
+}
+
+// Close disconnects from the database.
+func (s *ReadOnlyStorage) Close(ctx context.Context) error {
+	return s.upstream.Close(ctx)
+}
+
+// DropCollection returns ErrReadOnly.
+func (s *ReadOnlyStorage) DropCollection(context.Context, string) error {
+	return ErrReadOnly
+}
@@ -2,6 +2,11 @@ package mongostorage
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -12,69 +17,277 @@ import (
 	"go.uber.org/zap"
 )
 
-// RetryingStorage wraps StorageReaderWriter for read side
+var _ StorageReaderWriter = (*RetryingStorage)(nil)
+
+// RetryingStorage wraps StorageReaderWriter for read side. Writes are only retried when nothing was written: when a
+// node that isn't the primary rejected the command, e.g. during an election, or, for single-document writes, when it
+// conflicted with a concurrent write.
 type RetryingStorage struct {
 	upstream StorageReaderWriter
 	logger   *zap.Logger
+	config   RetryConfig
+
+	jitterMu sync.Mutex
+	jitter   *rand.Rand
+}
+
+// RetryConfig configures the retries of RetryingStorage. Zero fields keep the default behaviour.
+type RetryConfig struct {
+	// MaxRetries is the number of attempts before giving up, 10 by default. Negative retries until Budget runs out.
+	MaxRetries int
+	// BaseDelay is the pause after the first failed attempt, 10ms by default.
+	BaseDelay time.Duration
+	// MaxDelay caps the pause between attempts. Zero means no cap.
+	MaxDelay time.Duration
+	// Multiplier grows the pause exponentially after each failed attempt.
+	// Zero keeps the default linear growth of BaseDelay per attempt.
+	Multiplier float64
+	// Budget bounds the total time spent on an operation including retries. Zero means no bound.
+	Budget time.Duration
+	// Jitter picks each pause at random between zero and the computed delay ("full jitter"),
+	// so goroutines failing together don't retry in lockstep.
+	Jitter bool
+	// JitterSource provides the randomness for Jitter and for write conflicts, which are always retried with jitter,
+	// e.g. rand.NewSource(seed) for deterministic tests.
+	// Defaults to a time-seeded source.
+	JitterSource rand.Source
+	// OnRetry is called before every retry with the retried operation, e.g. "FindOne", its collection, the number
+	// of the failed attempt and its error, e.g. to count retries in metrics. Every retry is logged either way.
+	OnRetry func(operation, collection string, attempt int, err error)
 }
 
-// NewRetry creates new mongostorage with retries
-func NewRetry(upstream StorageReaderWriter, logger *zap.Logger) *RetryingStorage {
-	return &RetryingStorage{upstream: upstream, logger: logger}
+// NewRetry creates new mongostorage with retries.
+// An optional RetryConfig tunes the number of attempts and the backoff between them.
+func NewRetry(upstream StorageReaderWriter, logger *zap.Logger, config ...RetryConfig) *RetryingStorage {
+	var cfg RetryConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 10
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 10 * time.Millisecond
+	}
+
+	source := cfg.JitterSource
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+
+	storage := &RetryingStorage{upstream: upstream, logger: logger, config: cfg, jitter: rand.New(source)}
+
+	return storage
+}
+
+// NewRetryWithBudget creates new mongostorage that retries reads for up to budget in total instead of a fixed number
+// of attempts, doubling the pause between attempts from 10ms up to one second.
+// Like NewRetry, writes are only retried when nothing was written.
+func NewRetryWithBudget(upstream StorageReaderWriter, logger *zap.Logger, budget time.Duration) *RetryingStorage {
+	return NewRetry(upstream, logger, RetryConfig{
+		MaxRetries: -1,
+		MaxDelay:   time.Second,
+		Multiplier: 2,
+		Budget:     budget,
+	})
 }
 
 // FindOne returns a row into destination.
-func (s *RetryingStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	return s.retry(func() error {
-		return s.upstream.FindOne(ctx, collection, filter, dest)
+func (s *RetryingStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.retry(ctx, "FindOne", collection, func() error {
+		return s.upstream.FindOne(ctx, collection, filter, dest, opts...)
 	})
 }
 
 // FindAll returns all rows matching filter into destination.
-func (s *RetryingStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	return s.retry(func() error {
-		return s.upstream.FindAll(ctx, collection, filter, dest)
+func (s *RetryingStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.retry(ctx, "FindAll", collection, func() error {
+		return s.upstream.FindAll(ctx, collection, filter, dest, opts...)
 	})
 }
 
 // FindMany returns rows into destination.
-func (s *RetryingStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}) (total uint64, err error) {
-	err = s.retry(func() error {
-		total, err = s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, dest)
+func (s *RetryingStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...FindOption) (total uint64, err error) {
+	err = s.retry(ctx, "FindMany", collection, func() error {
+		total, err = s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, dest, opts...)
 		return err
 	})
 
 	return total, err
 }
 
+// Count returns the number of documents matching filter.
+func (s *RetryingStorage) Count(ctx context.Context, collection string, filter interface{}) (count uint64, err error) {
+	err = s.retry(ctx, "Count", collection, func() error {
+		count, err = s.upstream.Count(ctx, collection, filter)
+		return err
+	})
+
+	return count, err
+}
+
+// EstimatedCount returns the approximate number of documents in the collection.
+func (s *RetryingStorage) EstimatedCount(ctx context.Context, collection string) (count uint64, err error) {
+	err = s.retry(ctx, "EstimatedCount", collection, func() error {
+		count, err = s.upstream.EstimatedCount(ctx, collection)
+		return err
+	})
+
+	return count, err
+}
+
+// Distinct returns the unique values of field among documents matching filter.
+func (s *RetryingStorage) Distinct(ctx context.Context, collection string, field string, filter interface{}) (values []interface{}, err error) {
+	err = s.retry(ctx, "Distinct", collection, func() error {
+		values, err = s.upstream.Distinct(ctx, collection, field, filter)
+		return err
+	})
+
+	return values, err
+}
+
+// Exists reports whether a document matches filter.
+func (s *RetryingStorage) Exists(ctx context.Context, collection string, filter interface{}) (exists bool, err error) {
+	err = s.retry(ctx, "Exists", collection, func() error {
+		exists, err = s.upstream.Exists(ctx, collection, filter)
+		return err
+	})
+
+	return exists, err
+}
+
 // RunInTransaction encapsulates the function that needs to run in a transaction.
-func (s *RetryingStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
-	return s.upstream.RunInTransaction(ctx, fn)
+// Transient transaction errors are retried by Storage itself, so the call is passed through as is.
+func (s *RetryingStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...TxnOption) error {
+	return s.upstream.RunInTransaction(ctx, fn, opts...)
 }
 
 // Insert makes insert into database.
 func (s *RetryingStorage) Insert(ctx context.Context, collection string, document interface{}) error {
-	return s.upstream.Insert(ctx, collection, document)
+	return s.retryWrite(ctx, "Insert", collection, func() error {
+		return s.upstream.Insert(ctx, collection, document)
+	})
+}
+
+// InsertMany inserts documents into database in a single round trip.
+func (s *RetryingStorage) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...InsertManyOption) (insertedIDs []interface{}, err error) {
+	err = s.retryWriteMany(ctx, "InsertMany", collection, func() error {
+		insertedIDs, err = s.upstream.InsertMany(ctx, collection, documents, opts...)
+		return err
+	})
+
+	return insertedIDs, err
 }
 
 // Update updates documents in the database.
 func (s *RetryingStorage) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
-	return s.upstream.Update(ctx, collection, docID, update)
+	err = s.retryWrite(ctx, "Update", collection, func() error {
+		modifiedCount, err = s.upstream.Update(ctx, collection, docID, update)
+		return err
+	})
+
+	return modifiedCount, err
+}
+
+// UpdateMany updates all documents matching filter in the database.
+func (s *RetryingStorage) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	err = s.retryWriteMany(ctx, "UpdateMany", collection, func() error {
+		matchedCount, modifiedCount, err = s.upstream.UpdateMany(ctx, collection, filter, update)
+		return err
+	})
+
+	return matchedCount, modifiedCount, err
+}
+
+// FindOneAndUpdate atomically updates a single document and decodes it into destination.
+func (s *RetryingStorage) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error {
+	return s.retryWrite(ctx, "FindOneAndUpdate", collection, func() error {
+		return s.upstream.FindOneAndUpdate(ctx, collection, filter, update, dest, returnNew)
+	})
+}
+
+// FindOneAndDelete atomically deletes a single document and decodes it into destination.
+func (s *RetryingStorage) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error {
+	return s.retryWrite(ctx, "FindOneAndDelete", collection, func() error {
+		return s.upstream.FindOneAndDelete(ctx, collection, filter, sort, dest)
+	})
+}
+
+// FindOneAndReplace atomically replaces a single document and decodes it into destination.
+func (s *RetryingStorage) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error {
+	return s.retryWrite(ctx, "FindOneAndReplace", collection, func() error {
+		return s.upstream.FindOneAndReplace(ctx, collection, filter, replacement, returnNew, upsert, dest)
+	})
 }
 
 // Upsert updates or inserts document in the database.
 func (s *RetryingStorage) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
-	return s.upstream.Upsert(ctx, collection, docID, update)
+	err = s.retryWrite(ctx, "Upsert", collection, func() error {
+		upsertedCount, err = s.upstream.Upsert(ctx, collection, docID, update)
+		return err
+	})
+
+	return upsertedCount, err
+}
+
+// Replace replaces the whole document in the database.
+func (s *RetryingStorage) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	err = s.retryWrite(ctx, "Replace", collection, func() error {
+		modifiedCount, err = s.upstream.Replace(ctx, collection, docID, replacement)
+		return err
+	})
+
+	return modifiedCount, err
+}
+
+// ReplaceUpsert replaces or inserts the whole document in the database.
+func (s *RetryingStorage) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	err = s.retryWrite(ctx, "ReplaceUpsert", collection, func() error {
+		upsertedCount, err = s.upstream.ReplaceUpsert(ctx, collection, docID, replacement)
+		return err
+	})
+
+	return upsertedCount, err
 }
 
 // Delete deletes document in the database.
 func (s *RetryingStorage) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
-	return s.upstream.Delete(ctx, collection, docID)
+	err = s.retryWrite(ctx, "Delete", collection, func() error {
+		deletedCount, err = s.upstream.Delete(ctx, collection, docID)
+		return err
+	})
+
+	return deletedCount, err
 }
 
 // DeleteMany deletes filtered documents in the database.
 func (s *RetryingStorage) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
-	return s.upstream.DeleteMany(ctx, collection, filter)
+	err = s.retryWriteMany(ctx, "DeleteMany", collection, func() error {
+		deletedCount, err = s.upstream.DeleteMany(ctx, collection, filter)
+		return err
+	})
+
+	return deletedCount, err
+}
+
+// Truncate deletes every document of the collection.
+func (s *RetryingStorage) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	err = s.retryWriteMany(ctx, "Truncate", collection, func() error {
+		deletedCount, err = s.upstream.Truncate(ctx, collection)
+		return err
+	})
+
+	return deletedCount, err
+}
+
+// BulkWrite executes a batch of inserts, updates and deletes in a single command.
+func (s *RetryingStorage) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (result *mongo.BulkWriteResult, err error) {
+	err = s.retryWriteMany(ctx, "BulkWrite", collection, func() error {
+		result, err = s.upstream.BulkWrite(ctx, collection, models, ordered)
+		return err
+	})
+
+	return result, err
 }
 
 // GetDatabaseName returns the name of the current database.
@@ -82,15 +295,47 @@ func (s *RetryingStorage) GetDatabaseName() string {
 	return s.upstream.GetDatabaseName()
 }
 
-// retry keeps trying the function until the second argument returns false, or no error is returned.
-// Adapted from https://github.com/matryer/try/blob/master/try.go
-func (s *RetryingStorage) retry(fn func() (err error)) error {
-	const maxRetries = 10
+// Ping verifies the connection to the database is alive.
+func (s *RetryingStorage) Ping(ctx context.Context) error {
+	return s.upstream.Ping(ctx)
+}
 
+// Close disconnects from the database.
+func (s *RetryingStorage) Close(ctx context.Context) error {
+	return s.upstream.Close(ctx)
+}
+
+// DropCollection drops the collection together with its indexes.
+func (s *RetryingStorage) DropCollection(ctx context.Context, collection string) error {
+	return s.upstream.DropCollection(ctx, collection)
+}
+
+// retry keeps trying the read until it succeeds or fails with an error retryReason doesn't consider transient.
+func (s *RetryingStorage) retry(ctx context.Context, operation, collection string, fn func() (err error)) error {
+	return s.retryOn(ctx, operation, collection, retryReason, fn)
+}
+
+// retryWrite keeps trying the single-document write until it succeeds or fails with an error writeRetryReason
+// doesn't consider safe to retry.
+func (s *RetryingStorage) retryWrite(ctx context.Context, operation, collection string, fn func() (err error)) error {
+	return s.retryOn(ctx, operation, collection, writeRetryReason, fn)
+}
+
+// retryWriteMany keeps trying the multi-document write until it succeeds or fails with an error
+// writeManyRetryReason doesn't consider safe to retry.
+func (s *RetryingStorage) retryWriteMany(ctx context.Context, operation, collection string, fn func() (err error)) error {
+	return s.retryOn(ctx, operation, collection, writeManyRetryReason, fn)
+}
+
+// retryOn keeps trying the function until reason returns an empty string for its error, or no error is returned.
+// It gives up as soon as ctx is done, including while waiting between attempts.
+// Adapted from https://github.com/matryer/try/blob/master/try.go
+func (s *RetryingStorage) retryOn(ctx context.Context, operation, collection string, reason func(error) string, fn func() (err error)) error {
 	var err error
+	started := time.Now()
 	attempt := 1
 	for {
-		if attempt > maxRetries {
+		if s.config.MaxRetries > 0 && attempt > s.config.MaxRetries {
 			return errors.Wrap(err, "exceeded retry limit")
 		}
 
@@ -99,59 +344,213 @@ func (s *RetryingStorage) retry(fn func() (err error)) error {
 			return nil
 		}
 
-		if errors.Is(err, context.Canceled) {
+		// a done context is reported as a timeout by the driver, but retrying can't succeed
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) {
 			break
 		}
 
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			s.logger.Info("retrying mongodb client disconnected",
-				zap.Int("attempt", attempt), zap.String("error", err.Error()))
+		why := reason(err)
+		if why == "" {
+			// If we got here, we don't need to retry
+			break
+		}
 
-			time.Sleep(10 * time.Duration(attempt) * time.Millisecond)
-			attempt++
-			continue
+		// writers conflicting with each other would conflict again if they retried in lockstep
+		delay := s.delay(attempt, s.config.Jitter || isWriteConflict(err))
+		if s.config.Budget > 0 && time.Since(started)+delay > s.config.Budget {
+			return errors.Wrap(err, "exceeded retry budget")
 		}
 
-		if mongo.IsTimeout(err) {
-			s.logger.Info("retrying mongodb timeout",
-				zap.Int("attempt", attempt), zap.String("error", err.Error()))
+		s.logger.Info(why,
+			zap.String("operation", operation),
+			zap.String("collection", collection),
+			zap.Int("attempt", attempt),
+			zap.String("error", err.Error()))
+		if s.config.OnRetry != nil {
+			s.config.OnRetry(operation, collection, attempt, err)
+		}
 
-			time.Sleep(10 * time.Duration(attempt) * time.Millisecond)
-			attempt++
-			continue
+		if !sleep(ctx, delay) {
+			break
 		}
+		attempt++
+	}
 
-		if mongo.IsNetworkError(err) {
-			s.logger.Info("retrying mongodb network error",
-				zap.Int("attempt", attempt), zap.String("error", err.Error()))
+	// the attempt usually fails with a driver error not telling why it was cut short
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
 
-			time.Sleep(10 * time.Duration(attempt) * time.Millisecond)
-			attempt++
-			continue
-		}
+	return err
+}
 
-		if _, ok := err.(driver.RetryablePoolError); ok {
-			s.logger.Info("retrying mongodb pool error",
-				zap.Int("attempt", attempt), zap.String("error", err.Error()))
+// delay returns the pause after the given failed attempt, picked at random up to the computed delay when jitter is set.
+func (s *RetryingStorage) delay(attempt int, jitter bool) time.Duration {
+	var delay float64
+	if s.config.Multiplier == 0 {
+		delay = float64(s.config.BaseDelay) * float64(attempt)
+	} else {
+		delay = float64(s.config.BaseDelay) * math.Pow(s.config.Multiplier, float64(attempt-1))
+	}
 
-			time.Sleep(10 * time.Duration(attempt) * time.Millisecond)
-			attempt++
-			continue
-		}
+	if s.config.MaxDelay > 0 && delay > float64(s.config.MaxDelay) {
+		delay = float64(s.config.MaxDelay)
+	}
+	if delay >= math.MaxInt64 {
+		delay = math.MaxInt64 / 2
+	}
+
+	if jitter {
+		s.jitterMu.Lock()
+		delay *= s.jitter.Float64()
+		s.jitterMu.Unlock()
+	}
+
+	return time.Duration(delay)
+}
+
+// retryReason returns the log message describing why err is worth retrying, or an empty string if it isn't.
+func retryReason(err error) string {
+	// a missing document stays missing
+	if errors.Is(err, ErrNotFound) {
+		return ""
+	}
 
-		var waitQueueTimeoutError topology.WaitQueueTimeoutError
-		if errors.As(err, &waitQueueTimeoutError) {
-			s.logger.Info("retrying WaitQueueTimeoutError",
-				zap.Int("attempt", attempt), zap.String("error", err.Error()))
+	// and a malformed one stays malformed
+	var decodeError *DecodeError
+	if errors.As(err, &decodeError) {
+		return ""
+	}
+
+	if errors.Is(err, mongo.ErrClientDisconnected) {
+		return "retrying mongodb client disconnected"
+	}
+
+	if isNotPrimary(err) {
+		return "retrying mongodb not primary"
+	}
 
-			time.Sleep(10 * time.Duration(attempt) * time.Millisecond)
-			attempt++
-			continue
+	if isWriteConflict(err) {
+		return "retrying mongodb write conflict"
+	}
+
+	if mongo.IsTimeout(err) {
+		return "retrying mongodb timeout"
+	}
+
+	if mongo.IsNetworkError(err) {
+		return "retrying mongodb network error"
+	}
+
+	var retryablePoolError driver.RetryablePoolError
+	if errors.As(err, &retryablePoolError) {
+		return "retrying mongodb pool error"
+	}
+
+	var waitQueueTimeoutError topology.WaitQueueTimeoutError
+	if errors.As(err, &waitQueueTimeoutError) {
+		return "retrying WaitQueueTimeoutError"
+	}
+
+	// connections closed during brief network partitions surface as EOF
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return "retrying mongodb unexpected connection close"
+	}
+
+	return ""
+}
+
+// notPrimaryRejectionCodes are the server error codes of a node refusing a command before running it because it
+// isn't the primary, as happens during replica set elections.
+var notPrimaryRejectionCodes = []int{
+	10107, // NotWritablePrimary, formerly NotMaster
+	13435, // NotPrimaryNoSecondaryOk, formerly NotMasterNoSlaveOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// notPrimaryCodes adds to notPrimaryRejectionCodes the codes of an operation interrupted because the node stopped
+// being the primary. Such an operation may have partly run already.
+var notPrimaryCodes = []int{
+	10107, // NotWritablePrimary, formerly NotMaster
+	13435, // NotPrimaryNoSecondaryOk, formerly NotMasterNoSlaveOk
+	13436, // NotPrimaryOrSecondary
+	189,   // PrimarySteppedDown
+	11602, // InterruptedDueToReplStateChange
+}
+
+// isNotPrimary reports whether err was returned by a node that isn't, or stopped being, the primary, outside of
+// a transaction, which only RunInTransaction can run again.
+func isNotPrimary(err error) bool {
+	return hasServerErrorCode(err, notPrimaryCodes)
+}
+
+// hasServerErrorCode reports whether err carries one of the server error codes, outside of a transaction.
+func hasServerErrorCode(err error, codes []int) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) || hasErrorLabel(err, driver.TransientTransactionError) {
+		return false
+	}
+
+	for _, code := range codes {
+		if serverErr.HasErrorCode(code) {
+			return true
 		}
+	}
+
+	return false
+}
 
-		// If we got here, we don't need to retry
-		break
+// writeConflictCode is the server error code of a write conflicting with a concurrent one on the same document.
+const writeConflictCode = 112
+
+// isWriteConflict reports whether err is a write conflict outside of a transaction. A conflict aborts the transaction,
+// so only RunInTransaction can run it again; see WithTransactionAttempts.
+func isWriteConflict(err error) bool {
+	var serverErr mongo.ServerError
+
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(writeConflictCode) &&
+		!hasErrorLabel(err, driver.TransientTransactionError)
+}
+
+// writeRetryReason returns the log message describing why the single-document write failing with err is worth
+// retrying, or an empty string if it isn't. Only write conflicts and whole commands rejected before running for not being sent to the
+// primary qualify: the write may have been applied after any other failure, e.g. a timeout, a step down interrupting
+// it or a write concern error. The retryable writes of the driver cover the interrupted ones.
+func writeRetryReason(err error) string {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && hasServerErrorCode(commandErr, notPrimaryRejectionCodes) {
+		return "retrying mongodb write on not primary"
 	}
 
-	return err
+	// the conflicting write of the only document is rolled back, so nothing was written
+	if isWriteConflict(err) {
+		return "retrying mongodb write conflict"
+	}
+
+	return ""
+}
+
+// writeManyRetryReason works like writeRetryReason for writes of several documents, which only qualify when the
+// whole command failed. A write exception reports a failure of some of the documents while the others are
+// written, so retrying it would apply those a second time.
+func writeManyRetryReason(err error) string {
+	var commandErr mongo.CommandError
+	if !errors.As(err, &commandErr) {
+		return ""
+	}
+
+	return writeRetryReason(commandErr)
+}
+
+// sleep pauses for d and reports false when ctx is done before that.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
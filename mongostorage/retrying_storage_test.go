@@ -0,0 +1,100 @@
+package mongostorage_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/phoenixTW/go-mongodb-client/mongostorage"
+	"github.com/phoenixTW/go-mongodb-client/mongostorage/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRetryingStorageRetriesEOF(t *testing.T) {
+	calls := 0
+	upstream := &mock.MockedStorageReaderWriter{}
+	upstream.FindMock = func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) error {
+		calls++
+		if calls == 1 {
+			return io.EOF
+		}
+
+		return nil
+	}
+
+	storage := mongostorage.NewRetry(upstream, zap.NewNop())
+	err := storage.FindOne(context.Background(), "test", nil, &struct{}{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryingStorageStopsWhenContextIsDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		context func() (context.Context, context.CancelFunc)
+		want    error
+	}{
+		{
+			name:    "canceled",
+			context: func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			want:    context.Canceled,
+		},
+		{
+			name: "expired",
+			context: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Millisecond)
+			},
+			want: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.context()
+			defer cancel()
+
+			calls := 0
+			upstream := &mock.MockedStorageReaderWriter{}
+			upstream.FindMock = func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) error {
+				calls++
+				if tt.want == context.Canceled {
+					cancel()
+				}
+				<-ctx.Done()
+
+				return io.EOF
+			}
+
+			storage := mongostorage.NewRetry(upstream, zap.NewNop())
+			err := storage.FindOne(ctx, "test", nil, &struct{}{})
+
+			assert.ErrorIs(t, err, tt.want)
+			assert.ErrorIs(t, err, io.EOF)
+			assert.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestRetryingStorageStopsWaitingWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	upstream := &mock.MockedStorageReaderWriter{}
+	upstream.FindMock = func(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...mongostorage.FindOption) error {
+		calls++
+
+		return io.EOF
+	}
+
+	storage := mongostorage.NewRetry(upstream, zap.NewNop(), mongostorage.RetryConfig{
+		BaseDelay: time.Hour,
+		OnRetry:   func(string, string, int, error) { cancel() },
+	})
+	err := storage.FindOne(ctx, "test", nil, &struct{}{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
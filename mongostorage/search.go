@@ -0,0 +1,90 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TextScoreField is the field TextSearch stores the relevance score of every document in, to be decoded with e.g.
+// `bson:"score"` when needed.
+const TextScoreField = "score"
+
+// TextSearch returns up to limit documents matching the $text search of the collection into destination, the most
+// relevant first. A zero limit returns all matching documents. The collection must have a text index, see
+// CreateTextIndex; an error wrapping ErrIndexNotFound is returned otherwise.
+func (s *Storage) TextSearch(ctx context.Context, collection string, searchText string, limit uint64, dest interface{}) (err error) {
+	defer wrapError(&err, "TextSearch", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	findOptions := options.Find().
+		SetProjection(bson.M{TextScoreField: score}).
+		SetSort(bson.D{{Key: TextScoreField, Value: score}}).
+		SetLimit(int64(limit))
+
+	cursor, err := s.database.Collection(collection).Find(ctx, bson.M{"$text": bson.M{"$search": searchText}}, findOptions)
+	if err != nil {
+		return indexNotFound(err, fmt.Sprintf("text index of %s", collection))
+	}
+
+	return decodeCursor(ctx, collection, cursor, dest, nil)
+}
+
+// FindNear returns up to limit documents whose GeoJSON point field is within maxMeters of the given longitude and
+// latitude into destination, nearest first. A zero maxMeters doesn't bound the distance, and a zero limit returns all
+// such documents. The field must have a 2dsphere index; an error wrapping ErrIndexNotFound is returned otherwise.
+func (s *Storage) FindNear(
+	ctx context.Context,
+	collection string,
+	field string,
+	lng, lat float64,
+	maxMeters float64,
+	limit uint64,
+	dest interface{},
+) (err error) {
+	defer wrapError(&err, "FindNear", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	near := bson.M{"$geometry": bson.M{"type": "Point", "coordinates": bson.A{lng, lat}}}
+	if maxMeters > 0 {
+		near["$maxDistance"] = maxMeters
+	}
+
+	// $nearSphere sorts by distance itself
+	cursor, err := s.database.Collection(collection).
+		Find(ctx, bson.M{field: bson.M{"$nearSphere": near}}, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return geoIndexNotFound(err, fmt.Sprintf("2dsphere index on %s of %s", field, collection))
+	}
+
+	return decodeCursor(ctx, collection, cursor, dest, nil)
+}
+
+// geoIndexNotFound wraps the server error of a geospatial query without a geospatial index with ErrIndexNotFound.
+func geoIndexNotFound(err error, what string) error {
+	const noQueryExecutionPlansCode = 291
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(noQueryExecutionPlansCode) {
+		return fmt.Errorf("%s: %w: %w", what, ErrIndexNotFound, err)
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package mongostorage
+
+import (
+	"context"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StartCausalSession returns a child of ctx carrying a new causally consistent session, which every operation
+// run with the returned context uses, so reads observe the preceding writes even when served by a secondary.
+// end must be called once the context isn't used anymore. A session must not be used by concurrent goroutines.
+// RunInTransaction still runs in a session of its own.
+func (s *Storage) StartCausalSession(ctx context.Context) (sessCtx context.Context, end func(), err error) {
+	sess, err := s.database.Client().StartSession(options.Session().SetCausalConsistency(true))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mongo.NewSessionContext(ctx, sess), func() { sess.EndSession(ctx) }, nil
+}
+
+// CausalConsistencyMiddleware attaches a causally consistent session to the context of every request, giving
+// read-your-writes to all storage operations the handler runs with the request context; see StartCausalSession.
+// Requests are answered with 503 Service Unavailable when a session can't be started.
+func (s *Storage) CausalConsistencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, end, err := s.StartCausalSession(r.Context())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return
+		}
+		defer end()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
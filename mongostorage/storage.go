@@ -2,8 +2,10 @@ package mongostorage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,12 +13,14 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
 )
 
 // StorageReader describes interface for read operations for mongostorage
 type StorageReader interface {
-	FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error)
-	FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error)
+	FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error)
+	FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error)
 	FindMany(
 		ctx context.Context,
 		collection string,
@@ -24,17 +28,31 @@ type StorageReader interface {
 		limit, offset uint64,
 		sort string,
 		dest interface{},
+		opts ...FindOption,
 	) (total uint64, err error)
+	Count(ctx context.Context, collection string, filter interface{}) (uint64, error)
+	EstimatedCount(ctx context.Context, collection string) (uint64, error)
+	Distinct(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error)
+	Exists(ctx context.Context, collection string, filter interface{}) (bool, error)
 }
 
 // StorageWriter describes interface for write operations for mongostorage
 type StorageWriter interface {
-	RunInTransaction(ctx context.Context, fn func(context.Context) error) error
+	RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...TxnOption) error
 	Insert(ctx context.Context, collection string, document interface{}) error
+	InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...InsertManyOption) (insertedIDs []interface{}, err error)
 	Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error)
+	UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error)
+	FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error
+	FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error
+	FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error
 	Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error)
+	Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error)
+	ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error)
 	Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error)
 	DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error)
+	Truncate(ctx context.Context, collection string) (deletedCount int64, err error)
+	BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (*mongo.BulkWriteResult, error)
 }
 
 // StorageReaderWriter describes interface for both read and write operations for mongostorage
@@ -43,9 +61,14 @@ type StorageReaderWriter interface {
 	StorageWriter
 
 	GetDatabaseName() string
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+	DropCollection(ctx context.Context, collection string) error
 }
 
-// ObjectID will convert a string-compatible type to primitive.ObjectID
+// ObjectID will convert a string-compatible type to primitive.ObjectID.
+// An invalid hex string silently becomes the zero ObjectID, which matches no document; use ObjectIDChecked for
+// input that isn't validated yet.
 func ObjectID[T ~string](domainID T) primitive.ObjectID {
 	// nolint: errcheck // reason: we trust domain validation
 	objectID, _ := primitive.ObjectIDFromHex(string(domainID))
@@ -53,9 +76,51 @@ func ObjectID[T ~string](domainID T) primitive.ObjectID {
 	return objectID
 }
 
+// ObjectIDChecked will convert a string-compatible type to primitive.ObjectID, reporting an invalid hex string.
+func ObjectIDChecked[T ~string](domainID T) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(string(domainID))
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w %q: %w", ErrInvalidID, string(domainID), err)
+	}
+
+	return objectID, nil
+}
+
+// ObjectIDs will convert string-compatible types to primitive.ObjectIDs, e.g. for a $in query.
+// The error identifies the first invalid ID by its index.
+func ObjectIDs[T ~string](domainIDs []T) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(domainIDs))
+	for i, domainID := range domainIDs {
+		objectID, err := ObjectIDChecked(domainID)
+		if err != nil {
+			return nil, fmt.Errorf("ID at index %d: %w", i, err)
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	return objectIDs, nil
+}
+
+var _ StorageReaderWriter = (*Storage)(nil)
+
+// checkID rejects the zero ObjectID, which can't identify a document.
+func checkID(docID primitive.ObjectID) error {
+	if docID.IsZero() {
+		return ErrInvalidID
+	}
+
+	return nil
+}
+
 // Storage manages query builders and database requests.
+// Errors of its operations are annotated with the operation and collection, e.g. "FindOne(users): mongo: no documents
+// in result", and must be checked with errors.Is or errors.As rather than compared directly.
 type Storage struct {
-	database *mongo.Database
+	database            *mongo.Database
+	transactionAttempts int
+	maxDocumentDepth    int
+	writeConcern        *writeconcern.WriteConcern
+	operationTimeout    time.Duration
 }
 
 // GetDatabaseName returns the name of the current database
@@ -63,13 +128,67 @@ func (s *Storage) GetDatabaseName() string {
 	return s.database.Name()
 }
 
+// Ping verifies the connection to the primary is alive, bounded by the deadline of ctx.
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	return s.database.Client().Ping(ctx, readpref.Primary())
+}
+
+// Close disconnects the client of the database, waiting for in-use connections up to the deadline of ctx.
+// The storage, and every other storage sharing the client, can't be used anymore afterwards.
+func (s *Storage) Close(ctx context.Context) error {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	return s.database.Client().Disconnect(ctx)
+}
+
+// DropCollection drops the collection together with its indexes. Dropping a collection that doesn't exist succeeds.
+func (s *Storage) DropCollection(ctx context.Context, collection string) (err error) {
+	defer wrapError(&err, "DropCollection", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	return s.writeCollection(collection).Drop(ctx)
+}
+
+// Database returns the underlying database handle for operations the typed API doesn't cover,
+// e.g. cross-collection aggregations. It shares the connection managed by the storage.
+func (s *Storage) Database() *mongo.Database {
+	return s.database
+}
+
+// Collection returns the driver handle of the collection, with the write concern set by WithWriteConcern, for
+// queries the storage doesn't cover. Operations on it bypass the storage entirely: they aren't retried, timestamped
+// or recorded by the decorators wrapping the storage, and their errors aren't annotated.
+func (s *Storage) Collection(name string) *mongo.Collection {
+	return s.writeCollection(name)
+}
+
 // New initializes database mongostorage.
-func New(db *mongo.Database) StorageReaderWriter {
-	return &Storage{database: db}
+func New(db *mongo.Database, opts ...Option) *Storage {
+	storage := &Storage{database: db, transactionAttempts: defaultTransactionAttempts}
+	for _, opt := range opts {
+		opt(storage)
+	}
+
+	return storage
 }
 
 // RunInTransaction encapsulates the function that needs to run in a transaction.
-func (s *Storage) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
+// A panic in fn aborts the transaction and is returned as an error wrapping ErrCallbackPanicked.
+// Transactions failing with a TransientTransactionError are run again from the start, and commits with an
+// UnknownTransactionCommitResult are retried, as recommended by MongoDB; see WithTransactionAttempts.
+// The transaction reads from the primary with majority read concern unless tuned by opts.
+func (s *Storage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...TxnOption) error {
+	txnOptions := options.Transaction()
+	for _, opt := range opts {
+		opt(txnOptions)
+	}
+
 	sess, err := s.database.Client().StartSession(
 		// writeconcern is WMajority by default
 		options.Session().SetDefaultReadConcern(readconcern.Majority()),
@@ -81,21 +200,31 @@ func (s *Storage) RunInTransaction(ctx context.Context, fn func(context.Context)
 	}
 	defer sess.EndSession(ctx)
 
-	err = mongo.WithSession(ctx, sess, func(sessCtx mongo.SessionContext) error {
-		if err = sess.StartTransaction(); err != nil {
+	for attempt := 1; ; attempt++ {
+		err = s.runTransaction(ctx, sess, fn, txnOptions)
+		if err == nil || attempt >= s.transactionAttempts || !hasErrorLabel(err, driver.TransientTransactionError) {
+			return err
+		}
+	}
+}
+
+// runTransaction makes a single attempt to run fn in a transaction of the session.
+func (s *Storage) runTransaction(ctx context.Context, sess mongo.Session, fn func(context.Context) error, txnOptions *options.TransactionOptions) error {
+	err := mongo.WithSession(ctx, sess, func(sessCtx mongo.SessionContext) error {
+		if err := sess.StartTransaction(txnOptions); err != nil {
 			return err
 		}
 
-		if err = fn(sessCtx); err != nil {
+		if err := callSafely(func() error { return fn(sessCtx) }); err != nil {
 			return err
 		}
 
-		return sess.CommitTransaction(sessCtx)
+		return s.commitTransaction(sessCtx, sess)
 	})
 	if err != nil {
 		// abort fails if either the transaction was committed or already aborted (according to docs)
 		if abortErr := sess.AbortTransaction(ctx); abortErr != nil {
-			return fmt.Errorf("%w %v", abortErr, err)
+			return fmt.Errorf("%w %w", abortErr, err)
 		}
 
 		return err
@@ -104,22 +233,92 @@ func (s *Storage) RunInTransaction(ctx context.Context, fn func(context.Context)
 	return nil
 }
 
-// FindOne returns a row into destination.
-func (s *Storage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	return s.database.Collection(collection).FindOne(ctx, filter).Decode(dest)
+// commitTransaction commits the transaction, retrying while the outcome of the commit is unknown.
+func (s *Storage) commitTransaction(ctx context.Context, sess mongo.Session) error {
+	for attempt := 1; ; attempt++ {
+		err := sess.CommitTransaction(ctx)
+		if err == nil || attempt >= s.transactionAttempts || !hasErrorLabel(err, driver.UnknownTransactionCommitResult) {
+			return err
+		}
+	}
+}
+
+// hasErrorLabel reports whether an error in the chain carries the given server error label.
+func hasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
+
+// FindOne returns a row into destination. ErrNotFound is returned when nothing matched, and DecodeError when the row
+// doesn't fit destination.
+func (s *Storage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	defer wrapError(&err, "FindOne", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	cfg := newFindConfig(opts)
+	result := s.database.Collection(collection, cfg.collectionOptions()).FindOne(ctx, filter, cfg.findOneOptions())
+	if err = result.Err(); err != nil {
+		return notFound(err)
+	}
+
+	return decodeError(result.Decode(dest), collection, dest)
+}
+
+// MatchesFilter reports whether the document with docID exists and also satisfies filter, e.g. belongs to a tenant,
+// fetching only its _id.
+func (s *Storage) MatchesFilter(ctx context.Context, collection string, docID primitive.ObjectID, filter interface{}) (matches bool, err error) {
+	defer wrapError(&err, "MatchesFilter", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return false, err
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	err = s.database.Collection(collection).
+		FindOne(ctx, bson.M{"$and": bson.A{bson.M{"_id": docID}, filter}}, options.FindOne().SetProjection(bson.M{"_id": 1})).
+		Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // FindAll returns all rows matching filter into destination.
-func (s *Storage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}) (err error) {
-	cursor, err := s.database.Collection(collection).Find(ctx, filter)
+// A destination other than a non-nil pointer to a slice is rejected with ErrInvalidDestination.
+func (s *Storage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	defer wrapError(&err, "FindAll", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkSliceDestination(dest); err != nil {
+		return err
+	}
+
+	cfg := newFindConfig(opts)
+	cursor, err := s.database.Collection(collection, cfg.collectionOptions()).Find(ctx, filter, cfg.findOptions())
 	if err != nil {
 		return err
 	}
 
-	return cursor.All(ctx, dest)
+	return decodeCursor(ctx, collection, cursor, dest, nil)
 }
 
 // FindMany returns rows into destination.
+// The sort is a comma-separated list of fields, each optionally prefixed with "-" for descending order,
+// e.g. "status,-createdAt". It takes precedence over WithSort.
 func (s *Storage) FindMany(
 	ctx context.Context,
 	collection string,
@@ -127,41 +326,302 @@ func (s *Storage) FindMany(
 	limit, offset uint64,
 	sort string,
 	dest interface{},
+	opts ...FindOption,
 ) (total uint64, err error) {
-	count, err := s.database.Collection(collection).CountDocuments(ctx, filter)
+	defer wrapError(&err, "FindMany", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	cfg := newFindConfig(opts)
+	coll := s.database.Collection(collection, cfg.collectionOptions())
+
+	var count int64
+	if !cfg.withoutTotal {
+		count, err = coll.CountDocuments(ctx, filter, cfg.countOptions())
+		if err != nil {
+			return uint64(count), err
+		}
+	}
+
+	findOptions := cfg.findOptions().SetLimit(int64(limit)).SetSkip(int64(offset))
+	if sort != "" {
+		findOptions.SetSort(parseSort(sort))
+	}
+
+	cursor, err := coll.Find(ctx, filter, findOptions)
 	if err != nil {
 		return uint64(count), err
 	}
 
-	findOptions := options.Find().SetLimit(int64(limit)).SetSkip(int64(offset))
-	if sort != "" {
-		sortKey := sort
+	return uint64(count), decodeCursor(ctx, collection, cursor, dest, nil)
+}
+
+// Count returns the number of documents matching filter.
+func (s *Storage) Count(ctx context.Context, collection string, filter interface{}) (total uint64, err error) {
+	defer wrapError(&err, "Count", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	count, err := s.database.Collection(collection).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(count), nil
+}
+
+// EstimatedCount returns the approximate number of documents in the collection from its metadata,
+// which is fast on large collections but may be off, e.g. after an unclean shutdown.
+func (s *Storage) EstimatedCount(ctx context.Context, collection string) (total uint64, err error) {
+	defer wrapError(&err, "EstimatedCount", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	count, err := s.database.Collection(collection).EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(count), nil
+}
+
+// Distinct returns the unique values of field among documents matching filter, or an empty slice if none match.
+func (s *Storage) Distinct(ctx context.Context, collection string, field string, filter interface{}) (values []interface{}, err error) {
+	defer wrapError(&err, "Distinct", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	values, err = s.database.Collection(collection).Distinct(ctx, field, filter)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+
+	return values, nil
+}
+
+// Exists reports whether a document matches filter, fetching only its _id.
+func (s *Storage) Exists(ctx context.Context, collection string, filter interface{}) (exists bool, err error) {
+	defer wrapError(&err, "Exists", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	err = s.database.Collection(collection).FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// parseSort translates a sort expression such as "status,-createdAt" into the ordered sort document.
+func parseSort(sort string) bson.D {
+	sortDoc := bson.D{}
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
 		sortValue := 1
-		if strings.HasPrefix(sort, "-") {
-			sortKey = strings.TrimPrefix(sort, "-")
+		if strings.HasPrefix(field, "-") {
+			field = strings.TrimPrefix(field, "-")
 			sortValue = -1
 		}
-		findOptions.SetSort(bson.D{{Key: sortKey, Value: sortValue}})
+		sortDoc = append(sortDoc, bson.E{Key: field, Value: sortValue})
 	}
 
-	cursor, err := s.database.Collection(collection).Find(ctx, filter, findOptions)
+	return sortDoc
+}
+
+// Insert makes insert into database.
+func (s *Storage) Insert(ctx context.Context, collection string, document interface{}) (err error) {
+	defer wrapError(&err, "Insert", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = s.checkDepth(document); err != nil {
+		return err
+	}
+
+	_, err = s.writeCollection(collection).InsertOne(ctx, document)
+
+	return err
+}
+
+// IdempotencyKeyField is the document field InsertIdempotent stores the idempotency key in.
+// It must be covered by a unique index for the deduplication to hold.
+const IdempotencyKeyField = "idempotencyKey"
+
+// InsertIdempotent makes insert into database unless a document with the same idempotency key already exists,
+// so retried deliveries of the same insert don't create duplicates. It reports whether the document was inserted.
+func (s *Storage) InsertIdempotent(ctx context.Context, collection string, idempotencyKey string, document interface{}) (inserted bool, err error) {
+	defer wrapError(&err, "InsertIdempotent", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	doc, err := setField(document, IdempotencyKeyField, idempotencyKey, true)
 	if err != nil {
-		return uint64(count), err
+		return false, err
+	}
+	if err = s.checkDepth(doc); err != nil {
+		return false, err
 	}
 
-	return uint64(count), cursor.All(ctx, dest)
+	_, err = s.writeCollection(collection).InsertOne(ctx, doc)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	// the duplicate may be on another unique index, in which case the insert really failed
+	exists := s.database.Collection(collection).FindOne(ctx, bson.M{IdempotencyKeyField: idempotencyKey},
+		options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if exists != nil {
+		return false, err
+	}
+
+	return false, nil
 }
 
-// Insert makes insert into database.
-func (s *Storage) Insert(ctx context.Context, collection string, document interface{}) error {
-	_, err := s.database.Collection(collection).InsertOne(ctx, document)
+// InsertManyOption configures InsertMany.
+type InsertManyOption func(*options.InsertManyOptions)
 
-	return err
+// WithOrdered toggles ordered insertion. Unordered inserts continue past failing documents; the failures are reported
+// through a mongo.BulkWriteException whose WriteErrors carry the index of each rejected document.
+func WithOrdered(ordered bool) InsertManyOption {
+	return func(opts *options.InsertManyOptions) {
+		opts.SetOrdered(ordered)
+	}
+}
+
+// InsertMany inserts documents into database in a single round trip.
+// The returned IDs are in the order of documents, including the ones of documents that failed to insert.
+func (s *Storage) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...InsertManyOption) (insertedIDs []interface{}, err error) {
+	defer wrapError(&err, "InsertMany", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	for _, document := range documents {
+		if err = s.checkDepth(document); err != nil {
+			return nil, err
+		}
+	}
+
+	insertOptions := options.InsertMany()
+	for _, opt := range opts {
+		opt(insertOptions)
+	}
+
+	result, err := s.writeCollection(collection).InsertMany(ctx, documents, insertOptions)
+	if result != nil {
+		insertedIDs = result.InsertedIDs
+	}
+
+	return insertedIDs, err
+}
+
+// writeCollection returns the collection to write to, with the configured write concern if any.
+func (s *Storage) writeCollection(collection string) *mongo.Collection {
+	if s.writeConcern == nil {
+		return s.database.Collection(collection)
+	}
+
+	return s.database.Collection(collection, options.Collection().SetWriteConcern(s.writeConcern))
+}
+
+// operationContext bounds ctx by the timeout set with WithOperationTimeout, if any. The returned cancel function must
+// be called once the operation is done.
+func (s *Storage) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.operationTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	// a child context never outlives its parent, so an earlier deadline of ctx is kept
+	return context.WithTimeout(ctx, s.operationTimeout)
+}
+
+// checkDepth rejects the document with ErrDocumentTooDeep when it's nested deeper than the configured maximum.
+func (s *Storage) checkDepth(document interface{}) error {
+	if s.maxDocumentDepth == 0 {
+		return nil
+	}
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	if exceedsDepth(raw, s.maxDocumentDepth) {
+		return fmt.Errorf("%w, more than %d levels", ErrDocumentTooDeep, s.maxDocumentDepth)
+	}
+
+	return nil
 }
 
-// Update updates documents in the database.
+// checkUpdateDepth applies checkDepth to the documents an update document writes, i.e. those of $set and $setOnInsert.
+func (s *Storage) checkUpdateDepth(update interface{}) error {
+	if s.maxDocumentDepth == 0 || update == nil || isPipeline(update) {
+		return nil
+	}
+
+	doc, err := toDocument(update)
+	if err != nil {
+		return err
+	}
+	for _, elem := range doc {
+		if elem.Key != "$set" && elem.Key != "$setOnInsert" {
+			continue
+		}
+		if err = s.checkDepth(elem.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update updates documents in the database. A zero docID is rejected with ErrInvalidID.
 func (s *Storage) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
-	result, err := s.database.Collection(collection).UpdateOne(ctx, bson.M{"_id": docID}, update)
+	defer wrapError(&err, "Update", collection)
+
+	return s.update(ctx, collection, docID, update)
+}
+
+// update applies update to the document with docID, for Update and the helpers built on it.
+func (s *Storage) update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return 0, err
+	}
+	if err = s.checkUpdateDepth(update); err != nil {
+		return 0, err
+	}
+
+	result, err := s.writeCollection(collection).UpdateOne(ctx, bson.M{"_id": docID}, update)
 	if err != nil {
 		return 0, err
 	}
@@ -169,9 +629,111 @@ func (s *Storage) Update(ctx context.Context, collection string, docID primitive
 	return result.ModifiedCount, nil
 }
 
+// UpdateMany updates all documents matching filter in the database.
+func (s *Storage) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	defer wrapError(&err, "UpdateMany", collection)
+
+	return s.updateMany(ctx, collection, filter, update)
+}
+
+// updateMany applies update to all documents matching filter, for UpdateMany and the helpers built on it.
+func (s *Storage) updateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	result, err := s.writeCollection(collection).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return result.MatchedCount, result.ModifiedCount, nil
+}
+
+// FindOneAndUpdate atomically updates a single document matching filter and decodes it into destination,
+// as it was after the update when returnNew is set and before it otherwise.
+// ErrNotFound is returned when nothing matched.
+func (s *Storage) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) (err error) {
+	defer wrapError(&err, "FindOneAndUpdate", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	returnDocument := options.Before
+	if returnNew {
+		returnDocument = options.After
+	}
+
+	result := s.writeCollection(collection).
+		FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(returnDocument))
+	if err = result.Err(); err != nil {
+		return notFound(err)
+	}
+
+	return decodeError(result.Decode(dest), collection, dest)
+}
+
+// FindOneAndDelete atomically deletes a single document matching filter and decodes it into destination, e.g. to pop
+// the oldest item of a queue with the "createdAt" sort. The sort uses the FindMany syntax; an empty sort deletes any
+// matching document. ErrNotFound is returned when nothing matched.
+func (s *Storage) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) (err error) {
+	defer wrapError(&err, "FindOneAndDelete", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	deleteOptions := options.FindOneAndDelete()
+	if sort != "" {
+		deleteOptions.SetSort(parseSort(sort))
+	}
+
+	result := s.writeCollection(collection).FindOneAndDelete(ctx, filter, deleteOptions)
+	if err = result.Err(); err != nil {
+		return notFound(err)
+	}
+
+	return decodeError(result.Decode(dest), collection, dest)
+}
+
+// FindOneAndReplace atomically replaces a single document matching filter by replacement and decodes it into
+// destination, as it was after the replacement when returnNew is set and before it otherwise. With upsert,
+// replacement is inserted when nothing matched. ErrNotFound is returned when nothing matched, and also after an
+// insertion when returnNew isn't set, as there was no document before.
+func (s *Storage) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) (err error) {
+	defer wrapError(&err, "FindOneAndReplace", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = s.checkDepth(replacement); err != nil {
+		return err
+	}
+
+	returnDocument := options.Before
+	if returnNew {
+		returnDocument = options.After
+	}
+
+	replaceOptions := options.FindOneAndReplace().SetReturnDocument(returnDocument).SetUpsert(upsert)
+	result := s.writeCollection(collection).FindOneAndReplace(ctx, filter, replacement, replaceOptions)
+	if err = result.Err(); err != nil {
+		return notFound(err)
+	}
+
+	return decodeError(result.Decode(dest), collection, dest)
+}
+
 // Upsert updates or inserts document in the database.
 func (s *Storage) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
-	result, err := s.database.Collection(collection).UpdateOne(ctx, docID, update, options.Update().SetUpsert(true))
+	defer wrapError(&err, "Upsert", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = s.checkUpdateDepth(update); err != nil {
+		return 0, err
+	}
+
+	result, err := s.writeCollection(collection).UpdateOne(ctx, docID, update, options.Update().SetUpsert(true))
 	if err != nil {
 		return 0, err
 	}
@@ -179,9 +741,95 @@ func (s *Storage) Upsert(ctx context.Context, collection string, docID interface
 	return result.UpsertedCount, nil
 }
 
-// Delete deletes document in the database.
+// UpsertResult describes the outcome of UpsertReportingChange. Exactly one of the fields is set.
+type UpsertResult struct {
+	Created   bool
+	Modified  bool
+	Unchanged bool
+}
+
+// UpsertReportingChange updates or inserts document in the database and reports which of the outcomes happened.
+func (s *Storage) UpsertReportingChange(ctx context.Context, collection string, filter interface{}, update interface{}) (result UpsertResult, err error) {
+	defer wrapError(&err, "UpsertReportingChange", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	updateResult, err := s.writeCollection(collection).UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	switch {
+	case updateResult.UpsertedCount > 0:
+		result.Created = true
+	case updateResult.ModifiedCount > 0:
+		result.Modified = true
+	default:
+		result.Unchanged = true
+	}
+
+	return result, nil
+}
+
+// Replace replaces the whole document with docID by replacement, keeping its _id. A zero docID is rejected with ErrInvalidID.
+func (s *Storage) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	defer wrapError(&err, "Replace", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return 0, err
+	}
+
+	if err = s.checkDepth(replacement); err != nil {
+		return 0, err
+	}
+
+	result, err := s.writeCollection(collection).ReplaceOne(ctx, bson.M{"_id": docID}, replacement)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// ReplaceUpsert works like Replace, but inserts replacement with docID when there's no such document.
+func (s *Storage) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	defer wrapError(&err, "ReplaceUpsert", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return 0, err
+	}
+
+	if err = s.checkDepth(replacement); err != nil {
+		return 0, err
+	}
+
+	result, err := s.writeCollection(collection).ReplaceOne(ctx, bson.M{"_id": docID}, replacement, options.Replace().SetUpsert(true))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.UpsertedCount, nil
+}
+
+// Delete deletes document in the database. A zero docID is rejected with ErrInvalidID.
 func (s *Storage) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
-	result, err := s.database.Collection(collection).DeleteOne(ctx, bson.M{"_id": docID})
+	defer wrapError(&err, "Delete", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return 0, err
+	}
+
+	result, err := s.writeCollection(collection).DeleteOne(ctx, bson.M{"_id": docID})
 	if err != nil {
 		return 0, err
 	}
@@ -191,10 +839,62 @@ func (s *Storage) Delete(ctx context.Context, collection string, docID primitive
 
 // DeleteMany deletes filtered documents in the database.
 func (s *Storage) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
-	result, err := s.database.Collection(collection).DeleteMany(ctx, filter)
+	defer wrapError(&err, "DeleteMany", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	result, err := s.writeCollection(collection).DeleteMany(ctx, filter)
 	if err != nil {
 		return 0, err
 	}
 
 	return result.DeletedCount, nil
 }
+
+// Truncate deletes every document of the collection, keeping the collection itself and its indexes.
+func (s *Storage) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	defer wrapError(&err, "Truncate", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	result, err := s.writeCollection(collection).DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// BulkWrite executes a batch of inserts, updates and deletes in a single command, stopping at the first failure when
+// ordered is set.
+func (s *Storage) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (result *mongo.BulkWriteResult, err error) {
+	defer wrapError(&err, "BulkWrite", collection)
+
+	return s.bulkWrite(ctx, collection, models, ordered)
+}
+
+// bulkWrite executes the batch of writes, for BulkWrite and the helpers built on it.
+func (s *Storage) bulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (result *mongo.BulkWriteResult, err error) {
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	for _, model := range models {
+		switch model := model.(type) {
+		case *mongo.InsertOneModel:
+			err = s.checkDepth(model.Document)
+		case *mongo.ReplaceOneModel:
+			err = s.checkDepth(model.Replacement)
+		case *mongo.UpdateOneModel:
+			err = s.checkUpdateDepth(model.Update)
+		case *mongo.UpdateManyModel:
+			err = s.checkUpdateDepth(model.Update)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.writeCollection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+}
@@ -0,0 +1,76 @@
+package mongostorage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ForEach iterates documents matching filter one at a time, keeping memory bounded regardless of the result size.
+// Iteration stops at the first error returned by fn, which is then returned. The cursor is closed in every case,
+// including when fn panics. The raw document is only valid until fn returns; copy it to keep it around.
+func (s *Storage) ForEach(ctx context.Context, collection string, filter interface{}, fn func(raw bson.Raw) error) (err error) {
+	defer wrapError(&err, "ForEach", collection)
+
+	cursor, err := s.database.Collection(collection).Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		raw := cursor.Current
+		if err = callSafely(func() error { return fn(raw) }); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// ForEachBatch iterates documents matching filter in _id order, passing them to fn in chunks of up to batchSize.
+// Each chunk is fetched with a range query on _id rather than a skip, so scans stay fast deep into the collection
+// and can be resumed from the last processed _id. Iteration stops at the first error returned by fn.
+func (s *Storage) ForEachBatch(ctx context.Context, collection string, filter interface{}, batchSize int, fn func(batch []bson.Raw) error) (err error) {
+	defer wrapError(&err, "ForEachBatch", collection)
+
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(batchSize))
+	pageFilter := filter
+	for {
+		cursor, err := s.database.Collection(collection).Find(ctx, pageFilter, findOptions)
+		if err != nil {
+			return err
+		}
+
+		var batch []bson.Raw
+		if err = cursor.All(ctx, &batch); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err = callSafely(func() error { return fn(batch) }); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+
+		lastID := batch[len(batch)-1].Lookup("_id")
+		pageFilter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": lastID}}}}
+	}
+}
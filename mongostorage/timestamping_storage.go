@@ -0,0 +1,316 @@
+package mongostorage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Default timestamp field names used by TimestampingStorage.
+const (
+	DefaultCreatedAtField = "createdAt"
+	DefaultUpdatedAtField = "updatedAt"
+)
+
+var _ StorageReaderWriter = (*TimestampingStorage)(nil)
+
+// TimestampingStorage wraps StorageReaderWriter and maintains creation and modification timestamps on written documents
+type TimestampingStorage struct {
+	upstream       StorageReaderWriter
+	createdAtField string
+	updatedAtField string
+	clock          func() time.Time
+}
+
+// TimestampingOption configures optional behaviour of TimestampingStorage.
+type TimestampingOption func(*TimestampingStorage)
+
+// WithClock makes the storage take timestamps from clock instead of the wall clock, e.g. to get deterministic tests.
+func WithClock(clock func() time.Time) TimestampingOption {
+	return func(s *TimestampingStorage) {
+		s.clock = clock
+	}
+}
+
+// NewTimestamping creates new mongostorage that sets createdAtField when a document is inserted, including by an
+// upsert, and refreshes updatedAtField on every write. Empty field names fall back to DefaultCreatedAtField and
+// DefaultUpdatedAtField.
+func NewTimestamping(upstream StorageReaderWriter, createdAtField, updatedAtField string, opts ...TimestampingOption) *TimestampingStorage {
+	if createdAtField == "" {
+		createdAtField = DefaultCreatedAtField
+	}
+	if updatedAtField == "" {
+		updatedAtField = DefaultUpdatedAtField
+	}
+
+	storage := &TimestampingStorage{upstream: upstream, createdAtField: createdAtField, updatedAtField: updatedAtField, clock: time.Now}
+	for _, opt := range opts {
+		opt(storage)
+	}
+
+	return storage
+}
+
+// FindOne returns a row into destination.
+func (s *TimestampingStorage) FindOne(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.upstream.FindOne(ctx, collection, filter, dest, opts...)
+}
+
+// FindAll returns all rows matching filter into destination.
+func (s *TimestampingStorage) FindAll(ctx context.Context, collection string, filter interface{}, dest interface{}, opts ...FindOption) (err error) {
+	return s.upstream.FindAll(ctx, collection, filter, dest, opts...)
+}
+
+// FindMany returns rows into destination.
+func (s *TimestampingStorage) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, dest interface{}, opts ...FindOption) (total uint64, err error) {
+	return s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, dest, opts...)
+}
+
+// Count returns the number of documents matching filter.
+func (s *TimestampingStorage) Count(ctx context.Context, collection string, filter interface{}) (uint64, error) {
+	return s.upstream.Count(ctx, collection, filter)
+}
+
+// EstimatedCount returns the approximate number of documents in the collection.
+func (s *TimestampingStorage) EstimatedCount(ctx context.Context, collection string) (uint64, error) {
+	return s.upstream.EstimatedCount(ctx, collection)
+}
+
+// Distinct returns the unique values of field among documents matching filter.
+func (s *TimestampingStorage) Distinct(ctx context.Context, collection string, field string, filter interface{}) ([]interface{}, error) {
+	return s.upstream.Distinct(ctx, collection, field, filter)
+}
+
+// Exists reports whether a document matches filter.
+func (s *TimestampingStorage) Exists(ctx context.Context, collection string, filter interface{}) (bool, error) {
+	return s.upstream.Exists(ctx, collection, filter)
+}
+
+// RunInTransaction encapsulates the function that needs to run in a transaction.
+func (s *TimestampingStorage) RunInTransaction(ctx context.Context, fn func(context.Context) error, opts ...TxnOption) error {
+	return s.upstream.RunInTransaction(ctx, fn, opts...)
+}
+
+// Insert makes insert into database, setting the creation timestamp unless the document already carries one
+// and the modification timestamp.
+func (s *TimestampingStorage) Insert(ctx context.Context, collection string, document interface{}) error {
+	document, err := s.stampDocument(document, s.now())
+	if err != nil {
+		return err
+	}
+
+	return s.upstream.Insert(ctx, collection, document)
+}
+
+// InsertMany inserts documents into database in a single round trip, setting the timestamps on each of them like Insert.
+func (s *TimestampingStorage) InsertMany(ctx context.Context, collection string, documents []interface{}, opts ...InsertManyOption) (insertedIDs []interface{}, err error) {
+	now := s.now()
+	stamped := make([]interface{}, 0, len(documents))
+	for _, document := range documents {
+		document, err := s.stampDocument(document, now)
+		if err != nil {
+			return nil, err
+		}
+		stamped = append(stamped, document)
+	}
+
+	return s.upstream.InsertMany(ctx, collection, stamped, opts...)
+}
+
+// Update updates documents in the database, refreshing the modification timestamp.
+func (s *TimestampingStorage) Update(ctx context.Context, collection string, docID primitive.ObjectID, update interface{}) (modifiedCount int64, err error) {
+	update, err = setOperatorField(update, "$set", s.updatedAtField, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	return s.upstream.Update(ctx, collection, docID, update)
+}
+
+// UpdateMany updates all documents matching filter in the database, refreshing the modification timestamp.
+func (s *TimestampingStorage) UpdateMany(ctx context.Context, collection string, filter interface{}, update interface{}) (matchedCount, modifiedCount int64, err error) {
+	update, err = setOperatorField(update, "$set", s.updatedAtField, s.now())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return s.upstream.UpdateMany(ctx, collection, filter, update)
+}
+
+// FindOneAndUpdate atomically updates a single document and decodes it into destination, refreshing the modification timestamp.
+func (s *TimestampingStorage) FindOneAndUpdate(ctx context.Context, collection string, filter interface{}, update interface{}, dest interface{}, returnNew bool) error {
+	update, err := setOperatorField(update, "$set", s.updatedAtField, s.now())
+	if err != nil {
+		return err
+	}
+
+	return s.upstream.FindOneAndUpdate(ctx, collection, filter, update, dest, returnNew)
+}
+
+// FindOneAndDelete atomically deletes a single document and decodes it into destination.
+func (s *TimestampingStorage) FindOneAndDelete(ctx context.Context, collection string, filter interface{}, sort string, dest interface{}) error {
+	return s.upstream.FindOneAndDelete(ctx, collection, filter, sort, dest)
+}
+
+// FindOneAndReplace atomically replaces a single document and decodes it into destination, setting the modification
+// timestamp like Replace.
+func (s *TimestampingStorage) FindOneAndReplace(ctx context.Context, collection string, filter interface{}, replacement interface{}, returnNew, upsert bool, dest interface{}) error {
+	replacement, err := setField(replacement, s.updatedAtField, s.now(), true)
+	if err != nil {
+		return err
+	}
+
+	return s.upstream.FindOneAndReplace(ctx, collection, filter, replacement, returnNew, upsert, dest)
+}
+
+// Upsert updates or inserts document in the database, refreshing the modification timestamp and setting the
+// creation timestamp when the document gets inserted.
+func (s *TimestampingStorage) Upsert(ctx context.Context, collection string, docID interface{}, update interface{}) (upsertedCount int64, err error) {
+	update, err = s.stampUpsert(update, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	return s.upstream.Upsert(ctx, collection, docID, update)
+}
+
+// Replace replaces the whole document in the database, setting the modification timestamp. The creation timestamp
+// is kept only if replacement carries it.
+func (s *TimestampingStorage) Replace(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (modifiedCount int64, err error) {
+	replacement, err = setField(replacement, s.updatedAtField, s.now(), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.upstream.Replace(ctx, collection, docID, replacement)
+}
+
+// ReplaceUpsert replaces or inserts the whole document in the database, setting the modification timestamp like Replace.
+func (s *TimestampingStorage) ReplaceUpsert(ctx context.Context, collection string, docID primitive.ObjectID, replacement interface{}) (upsertedCount int64, err error) {
+	replacement, err = setField(replacement, s.updatedAtField, s.now(), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.upstream.ReplaceUpsert(ctx, collection, docID, replacement)
+}
+
+// Delete deletes document in the database.
+func (s *TimestampingStorage) Delete(ctx context.Context, collection string, docID primitive.ObjectID) (deletedCount int64, err error) {
+	return s.upstream.Delete(ctx, collection, docID)
+}
+
+// DeleteMany deletes filtered documents in the database.
+func (s *TimestampingStorage) DeleteMany(ctx context.Context, collection string, filter interface{}) (deletedCount int64, err error) {
+	return s.upstream.DeleteMany(ctx, collection, filter)
+}
+
+// Truncate deletes every document of the collection.
+func (s *TimestampingStorage) Truncate(ctx context.Context, collection string) (deletedCount int64, err error) {
+	return s.upstream.Truncate(ctx, collection)
+}
+
+// BulkWrite executes a batch of inserts, updates and deletes in a single command, timestamping the written documents
+// like the corresponding single operations do.
+func (s *TimestampingStorage) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel, ordered bool) (*mongo.BulkWriteResult, error) {
+	now := s.now()
+	stamped := make([]mongo.WriteModel, 0, len(models))
+	for _, model := range models {
+		model, err := s.stampModel(model, now)
+		if err != nil {
+			return nil, err
+		}
+		stamped = append(stamped, model)
+	}
+
+	return s.upstream.BulkWrite(ctx, collection, stamped, ordered)
+}
+
+// stampModel returns a copy of the write model with timestamps set on its document or update.
+func (s *TimestampingStorage) stampModel(model mongo.WriteModel, now time.Time) (mongo.WriteModel, error) {
+	var err error
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		stamped := *m
+		stamped.Document, err = s.stampDocument(m.Document, now)
+		return &stamped, err
+	case *mongo.UpdateOneModel:
+		stamped := *m
+		stamped.Update, err = s.stampUpdate(m.Update, m.Upsert, now)
+		return &stamped, err
+	case *mongo.UpdateManyModel:
+		stamped := *m
+		stamped.Update, err = s.stampUpdate(m.Update, m.Upsert, now)
+		return &stamped, err
+	case *mongo.ReplaceOneModel:
+		stamped := *m
+		stamped.Replacement, err = setField(m.Replacement, s.updatedAtField, now, true)
+		return &stamped, err
+	}
+
+	return model, nil
+}
+
+// stampDocument returns the new document with the creation timestamp set unless it already carries one and the
+// modification timestamp set.
+func (s *TimestampingStorage) stampDocument(document interface{}, now time.Time) (bson.D, error) {
+	doc, err := setField(document, s.createdAtField, now, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return setField(doc, s.updatedAtField, now, true)
+}
+
+// stampUpdate returns the update with timestamps set, as by stampUpsert when upsert is set.
+func (s *TimestampingStorage) stampUpdate(update interface{}, upsert *bool, now time.Time) (interface{}, error) {
+	if upsert != nil && *upsert {
+		return s.stampUpsert(update, now)
+	}
+
+	return setOperatorField(update, "$set", s.updatedAtField, now)
+}
+
+// stampUpsert returns the update with the modification timestamp set and the creation timestamp set on insert.
+func (s *TimestampingStorage) stampUpsert(update interface{}, now time.Time) (interface{}, error) {
+	update, err := setOperatorField(update, "$set", s.updatedAtField, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPipeline(update) {
+		// $setOnInsert isn't a pipeline stage, keep an existing value instead
+		createdAt := bson.D{{Key: "$ifNull", Value: bson.A{"$" + s.createdAtField, now}}}
+		return setOperatorField(update, "$set", s.createdAtField, createdAt)
+	}
+
+	return setOperatorField(update, "$setOnInsert", s.createdAtField, now)
+}
+
+// GetDatabaseName returns the name of the current database.
+func (s *TimestampingStorage) GetDatabaseName() string {
+	return s.upstream.GetDatabaseName()
+}
+
+// Ping verifies the connection to the database is alive.
+func (s *TimestampingStorage) Ping(ctx context.Context) error {
+	return s.upstream.Ping(ctx)
+}
+
+// Close disconnects from the database.
+func (s *TimestampingStorage) Close(ctx context.Context) error {
+	return s.upstream.Close(ctx)
+}
+
+// DropCollection drops the collection together with its indexes.
+func (s *TimestampingStorage) DropCollection(ctx context.Context, collection string) error {
+	return s.upstream.DropCollection(ctx, collection)
+}
+
+// now returns the current time truncated to the millisecond precision stored by MongoDB.
+func (s *TimestampingStorage) now() time.Time {
+	return s.clock().UTC().Truncate(time.Millisecond)
+}
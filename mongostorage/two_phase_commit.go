@@ -0,0 +1,251 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PendingTransactionsField is the document field TwoPhaseCommit tracks the transactions applied to a document in.
+const PendingTransactionsField = "pendingTransactions"
+
+// States of a transaction document written by TwoPhaseCommit.
+const (
+	TwoPhaseInitial   = "initial"
+	TwoPhasePending   = "pending"
+	TwoPhaseApplied   = "applied"
+	TwoPhaseDone      = "done"
+	TwoPhaseCanceling = "canceling"
+	TwoPhaseCanceled  = "canceled"
+)
+
+// TwoPhaseOperation is a change of a single document made by TwoPhaseCommit. Update and Rollback are update documents;
+// Rollback must revert Update and is applied to documents already changed when the commit can't complete.
+type TwoPhaseOperation struct {
+	Collection string
+	DocID      primitive.ObjectID
+	Update     interface{}
+	Rollback   interface{}
+}
+
+// twoPhaseTarget is an operation as recorded in the transaction document, with the rollback needed to recover it.
+type twoPhaseTarget struct {
+	Collection string             `bson:"collection"`
+	DocID      primitive.ObjectID `bson:"docId"`
+	Rollback   interface{}        `bson:"rollback,omitempty"`
+}
+
+// TwoPhaseCommit applies the operations across documents and collections without a multi-document transaction,
+// following the two-phase commit pattern: the progress is recorded in a document of logCollection moving through
+// the initial, pending, applied and done states, and every changed document is tagged with the transaction ID in
+// PendingTransactionsField until all operations are applied, so no operation is applied twice. When an operation
+// can't be applied, the applied ones are rolled back and the transaction ends up canceled. It returns the ID of the
+// transaction document.
+//
+// A transaction left unfinished by a crash stays in its state, with its documents tagged, until
+// RecoverTwoPhaseCommits completes or rolls it back.
+//
+// Unlike RunInTransaction, the intermediate states are visible to other readers. Prefer RunInTransaction whenever
+// the deployment supports transactions, i.e. on replica sets and sharded clusters.
+func (s *Storage) TwoPhaseCommit(ctx context.Context, logCollection string, operations []TwoPhaseOperation) (txnID primitive.ObjectID, err error) {
+	defer wrapError(&err, "TwoPhaseCommit", logCollection)
+
+	targets := make([]twoPhaseTarget, 0, len(operations))
+	for _, op := range operations {
+		if err = checkID(op.DocID); err != nil {
+			return primitive.NilObjectID, err
+		}
+		if isPipeline(op.Update) || (op.Rollback != nil && isPipeline(op.Rollback)) {
+			return primitive.NilObjectID, errors.New("two-phase commit doesn't support pipeline updates")
+		}
+		targets = append(targets, twoPhaseTarget{Collection: op.Collection, DocID: op.DocID, Rollback: op.Rollback})
+	}
+
+	txnID = primitive.NewObjectID()
+	txn := bson.M{"_id": txnID, "state": TwoPhaseInitial, "operations": targets, "lastModified": time.Now()}
+	if _, err = s.writeCollection(logCollection).InsertOne(ctx, txn); err != nil {
+		return txnID, err
+	}
+	if err = s.setTwoPhaseState(ctx, logCollection, txnID, TwoPhaseInitial, TwoPhasePending); err != nil {
+		return txnID, err
+	}
+
+	for i, op := range operations {
+		if err = s.applyTwoPhaseOperation(ctx, txnID, op); err != nil {
+			// a failed update may still have been applied, e.g. after a timeout
+			if rollbackErr := s.cancelTwoPhaseCommit(ctx, logCollection, txnID, operations[:i+1]); rollbackErr != nil {
+				return txnID, fmt.Errorf("%w, rollback failed: %w", err, rollbackErr)
+			}
+
+			return txnID, err
+		}
+	}
+
+	if err = s.setTwoPhaseState(ctx, logCollection, txnID, TwoPhasePending, TwoPhaseApplied); err != nil {
+		return txnID, err
+	}
+	for _, op := range operations {
+		if err = s.releaseTwoPhaseDocument(ctx, txnID, op); err != nil {
+			return txnID, err
+		}
+	}
+
+	return txnID, s.setTwoPhaseState(ctx, logCollection, txnID, TwoPhaseApplied, TwoPhaseDone)
+}
+
+// applyTwoPhaseOperation applies the update of op unless the document was already changed by the transaction.
+func (s *Storage) applyTwoPhaseOperation(ctx context.Context, txnID primitive.ObjectID, op TwoPhaseOperation) error {
+	update, err := setOperatorField(op.Update, "$push", PendingTransactionsField, txnID)
+	if err != nil {
+		return err
+	}
+
+	coll := s.writeCollection(op.Collection)
+	filter := bson.M{"_id": op.DocID, PendingTransactionsField: bson.M{"$ne": txnID}}
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	// not matching may also mean the operation was applied already
+	applied, err := coll.CountDocuments(ctx, bson.M{"_id": op.DocID, PendingTransactionsField: txnID})
+	if err != nil {
+		return err
+	}
+	if applied == 0 {
+		return fmt.Errorf("document %s of %s not found", op.DocID.Hex(), op.Collection)
+	}
+
+	return nil
+}
+
+// releaseTwoPhaseDocument removes the transaction tag from the document changed by op.
+func (s *Storage) releaseTwoPhaseDocument(ctx context.Context, txnID primitive.ObjectID, op TwoPhaseOperation) error {
+	_, err := s.writeCollection(op.Collection).UpdateOne(ctx,
+		bson.M{"_id": op.DocID, PendingTransactionsField: txnID},
+		bson.M{"$pull": bson.M{PendingTransactionsField: txnID}})
+
+	return err
+}
+
+// cancelTwoPhaseCommit rolls back the applied operations and marks the transaction canceled.
+func (s *Storage) cancelTwoPhaseCommit(ctx context.Context, logCollection string, txnID primitive.ObjectID, applied []TwoPhaseOperation) error {
+	if err := s.setTwoPhaseState(ctx, logCollection, txnID, TwoPhasePending, TwoPhaseCanceling); err != nil {
+		return err
+	}
+
+	return s.rollbackTwoPhaseCommit(ctx, logCollection, txnID, applied)
+}
+
+// rollbackTwoPhaseCommit rolls back the operations that may have been applied by the canceling transaction and marks
+// it canceled.
+func (s *Storage) rollbackTwoPhaseCommit(ctx context.Context, logCollection string, txnID primitive.ObjectID, applied []TwoPhaseOperation) error {
+	for _, op := range applied {
+		if op.Rollback == nil {
+			// nothing to revert when the update wasn't applied
+			tagged, err := s.writeCollection(op.Collection).
+				CountDocuments(ctx, bson.M{"_id": op.DocID, PendingTransactionsField: txnID})
+			if err != nil {
+				return err
+			}
+			if tagged == 0 {
+				continue
+			}
+
+			return fmt.Errorf("no rollback for document %s of %s", op.DocID.Hex(), op.Collection)
+		}
+
+		rollback, err := setOperatorField(op.Rollback, "$pull", PendingTransactionsField, txnID)
+		if err != nil {
+			return err
+		}
+
+		// only documents still tagged with the transaction have the update applied
+		filter := bson.M{"_id": op.DocID, PendingTransactionsField: txnID}
+		if _, err = s.writeCollection(op.Collection).UpdateOne(ctx, filter, rollback); err != nil {
+			return err
+		}
+	}
+
+	return s.setTwoPhaseState(ctx, logCollection, txnID, TwoPhaseCanceling, TwoPhaseCanceled)
+}
+
+// RecoverTwoPhaseCommits finishes the transactions of TwoPhaseCommit in logCollection left unfinished, typically by a
+// crash, for longer than olderThan: applied transactions are completed, and the others rolled back and canceled. It
+// returns the number of recovered transactions. olderThan must comfortably exceed the duration of a TwoPhaseCommit,
+// so transactions still in progress aren't recovered concurrently.
+func (s *Storage) RecoverTwoPhaseCommits(ctx context.Context, logCollection string, olderThan time.Duration) (recovered int, err error) {
+	defer wrapError(&err, "RecoverTwoPhaseCommits", logCollection)
+
+	filter := bson.M{
+		"state":        bson.M{"$in": bson.A{TwoPhaseInitial, TwoPhasePending, TwoPhaseApplied, TwoPhaseCanceling}},
+		"lastModified": bson.M{"$lt": time.Now().Add(-olderThan)},
+	}
+	cursor, err := s.database.Collection(logCollection).Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var txns []struct {
+		ID         primitive.ObjectID `bson:"_id"`
+		State      string             `bson:"state"`
+		Operations []twoPhaseTarget   `bson:"operations"`
+	}
+	if err = cursor.All(ctx, &txns); err != nil {
+		return 0, err
+	}
+
+	for _, txn := range txns {
+		operations := make([]TwoPhaseOperation, 0, len(txn.Operations))
+		for _, target := range txn.Operations {
+			operations = append(operations, TwoPhaseOperation{Collection: target.Collection, DocID: target.DocID, Rollback: target.Rollback})
+		}
+
+		switch txn.State {
+		case TwoPhaseInitial:
+			// no operation is applied before the transaction is pending
+			err = s.setTwoPhaseState(ctx, logCollection, txn.ID, TwoPhaseInitial, TwoPhaseCanceled)
+		case TwoPhasePending:
+			err = s.cancelTwoPhaseCommit(ctx, logCollection, txn.ID, operations)
+		case TwoPhaseCanceling:
+			err = s.rollbackTwoPhaseCommit(ctx, logCollection, txn.ID, operations)
+		case TwoPhaseApplied:
+			for _, op := range operations {
+				if err = s.releaseTwoPhaseDocument(ctx, txn.ID, op); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = s.setTwoPhaseState(ctx, logCollection, txn.ID, TwoPhaseApplied, TwoPhaseDone)
+			}
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("recovering transaction %s: %w", txn.ID.Hex(), err)
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+// setTwoPhaseState moves the transaction document from one state to the next.
+func (s *Storage) setTwoPhaseState(ctx context.Context, logCollection string, txnID primitive.ObjectID, from, to string) error {
+	result, err := s.writeCollection(logCollection).UpdateOne(ctx,
+		bson.M{"_id": txnID, "state": from},
+		bson.M{"$set": bson.M{"state": to}, "$currentDate": bson.M{"lastModified": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("transaction %s is not %s", txnID.Hex(), from)
+	}
+
+	return nil
+}
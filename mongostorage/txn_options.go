@@ -0,0 +1,34 @@
+package mongostorage
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// TxnOption configures a transaction run by RunInTransaction.
+type TxnOption func(*options.TransactionOptions)
+
+// WithTxnReadConcern sets the read concern of the transaction. Defaults to majority.
+func WithTxnReadConcern(readConcern *readconcern.ReadConcern) TxnOption {
+	return func(opts *options.TransactionOptions) {
+		opts.SetReadConcern(readConcern)
+	}
+}
+
+// WithTxnWriteConcern sets the write concern of the transaction. Defaults to the write concern of the client,
+// majority unless configured otherwise.
+func WithTxnWriteConcern(writeConcern *writeconcern.WriteConcern) TxnOption {
+	return func(opts *options.TransactionOptions) {
+		opts.SetWriteConcern(writeConcern)
+	}
+}
+
+// WithMaxCommitTime bounds how long a single commit of the transaction may take on the server.
+func WithMaxCommitTime(maxCommitTime time.Duration) TxnOption {
+	return func(opts *options.TransactionOptions) {
+		opts.SetMaxCommitTime(&maxCommitTime)
+	}
+}
@@ -0,0 +1,57 @@
+package mongostorage
+
+import "context"
+
+// TypedStorage wraps StorageReaderWriter for documents of type T, decoding results into T without a destination
+// argument. Operations not covered here are available on the wrapped storage.
+type TypedStorage[T any] struct {
+	upstream StorageReaderWriter
+}
+
+// NewTyped creates new mongostorage for documents of type T
+func NewTyped[T any](upstream StorageReaderWriter) *TypedStorage[T] {
+	return &TypedStorage[T]{upstream: upstream}
+}
+
+// Upstream returns the wrapped storage.
+func (s *TypedStorage[T]) Upstream() StorageReaderWriter {
+	return s.upstream
+}
+
+// FindOne returns the document matching filter. ErrNotFound is returned when nothing matched.
+func (s *TypedStorage[T]) FindOne(ctx context.Context, collection string, filter interface{}, opts ...FindOption) (T, error) {
+	var doc T
+	if err := s.upstream.FindOne(ctx, collection, filter, &doc, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return doc, nil
+}
+
+// FindAll returns all documents matching filter.
+func (s *TypedStorage[T]) FindAll(ctx context.Context, collection string, filter interface{}, opts ...FindOption) ([]T, error) {
+	docs := []T{}
+	if err := s.upstream.FindAll(ctx, collection, filter, &docs, opts...); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// FindMany returns a page of documents matching filter and the total number of matching documents,
+// see StorageReader.FindMany.
+func (s *TypedStorage[T]) FindMany(ctx context.Context, collection string, filter interface{}, limit, offset uint64, sort string, opts ...FindOption) ([]T, uint64, error) {
+	docs := []T{}
+	total, err := s.upstream.FindMany(ctx, collection, filter, limit, offset, sort, &docs, opts...)
+	if err != nil {
+		return nil, total, err
+	}
+
+	return docs, total, nil
+}
+
+// Insert makes insert into database.
+func (s *TypedStorage[T]) Insert(ctx context.Context, collection string, doc T) error {
+	return s.upstream.Insert(ctx, collection, doc)
+}
@@ -0,0 +1,131 @@
+package mongostorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IncrementMany atomically increments several fields of the document with docID in one update, e.g.
+// {"views": 1, "impressions": 3}. ErrNotFound is returned when there's no such document.
+func (s *Storage) IncrementMany(ctx context.Context, collection string, docID primitive.ObjectID, increments map[string]int64) (err error) {
+	defer wrapError(&err, "IncrementMany", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return err
+	}
+	if len(increments) == 0 {
+		return errors.New("no fields to increment")
+	}
+
+	inc := bson.M{}
+	for field, delta := range increments {
+		inc[field] = delta
+	}
+
+	result, err := s.writeCollection(collection).UpdateOne(ctx, bson.M{"_id": docID}, bson.M{"$inc": inc})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
+// VersionField is the document field UpdateWithVersion compares and increments.
+const VersionField = "version"
+
+// UpdateWithVersion applies update to the document with docID only if its version still equals expectedVersion,
+// incrementing the version in the same update. It provides compare-and-swap semantics without a transaction:
+// ErrVersionConflict is returned when the document was changed concurrently or doesn't exist.
+func (s *Storage) UpdateWithVersion(ctx context.Context, collection string, docID primitive.ObjectID, expectedVersion int64, update interface{}) (modifiedCount int64, err error) {
+	defer wrapError(&err, "UpdateWithVersion", collection)
+
+	ctx, cancel := s.operationContext(ctx)
+	defer cancel()
+
+	if err = checkID(docID); err != nil {
+		return 0, err
+	}
+
+	if isPipeline(update) {
+		// $inc isn't a pipeline stage
+		update, err = setOperatorField(update, "$set", VersionField, bson.D{{Key: "$add", Value: bson.A{"$" + VersionField, 1}}})
+	} else {
+		update, err = setOperatorField(update, "$inc", VersionField, 1)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	filter := bson.M{"_id": docID, VersionField: expectedVersion}
+	result, err := s.writeCollection(collection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	if result.ModifiedCount == 0 {
+		return 0, ErrVersionConflict
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// RenameField renames the field from to to in every document that has it, e.g. as a schema migration.
+// An existing field named to is overwritten. It returns the number of modified documents.
+func (s *Storage) RenameField(ctx context.Context, collection, from, to string) (modified int64, err error) {
+	defer wrapError(&err, "RenameField", collection)
+
+	if from == "" || to == "" || from == to {
+		return 0, fmt.Errorf("invalid rename of %q to %q", from, to)
+	}
+
+	_, modified, err = s.updateMany(ctx, collection, bson.M{from: bson.M{"$exists": true}}, bson.M{"$rename": bson.M{from: to}})
+
+	return modified, err
+}
+
+// UpsertModel is a single upsert of UpsertMany: the document matching Filter is updated with Update, or replaced by
+// Replacement when it's set, and inserted when nothing matches.
+type UpsertModel struct {
+	Filter      interface{}
+	Update      interface{}
+	Replacement interface{}
+}
+
+// UpsertMany upserts every model in a single unordered BulkWrite, so a failing model doesn't stop the others.
+// The result tells the inserted documents, as UpsertedCount and UpsertedIDs, from the modified ones.
+func (s *Storage) UpsertMany(ctx context.Context, collection string, upserts []UpsertModel) (result *mongo.BulkWriteResult, err error) {
+	defer wrapError(&err, "UpsertMany", collection)
+
+	if len(upserts) == 0 {
+		return &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}, nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(upserts))
+	for i, upsert := range upserts {
+		switch {
+		case upsert.Replacement != nil && upsert.Update != nil:
+			return nil, fmt.Errorf("upsert %d has both an update and a replacement", i)
+		case upsert.Replacement != nil:
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(upsert.Filter).SetReplacement(upsert.Replacement).SetUpsert(true))
+		case upsert.Update != nil:
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(upsert.Filter).SetUpdate(upsert.Update).SetUpsert(true))
+		default:
+			return nil, fmt.Errorf("upsert %d has neither an update nor a replacement", i)
+		}
+	}
+
+	// bulkWrite checks the depth of the replacements and updates
+	return s.bulkWrite(ctx, collection, models, false)
+}
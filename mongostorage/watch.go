@@ -0,0 +1,188 @@
+package mongostorage
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChangeEvent is a change of a document delivered by Watch.
+type ChangeEvent struct {
+	// ResumeToken identifies the event; pass it to WithResumeAfter to continue watching after it
+	ResumeToken   bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	DocumentKey   bson.M   `bson:"documentKey"`
+	// FullDocument is set for inserts and replacements, and for updates with WithFullDocument
+	FullDocument bson.Raw `bson:"fullDocument,omitempty"`
+	// FullDocumentBeforeChange is only set with WithFullDocumentBeforeChange
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange,omitempty"`
+}
+
+// ResumeTokenStore persists the resume token of the last processed change event, so Watch continues where it left
+// off after a restart. Load returns an empty token when nothing was saved yet.
+type ResumeTokenStore interface {
+	Load(ctx context.Context) (bson.Raw, error)
+	Save(ctx context.Context, resumeToken bson.Raw) error
+}
+
+// WatchOption configures optional behaviour of Watch.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	streamOptions *options.ChangeStreamOptions
+	tokenStore    ResumeTokenStore
+}
+
+// WithResumeAfter starts the stream right after the event with the given resume token, so no event is missed
+// across restarts as long as the token is still in the oplog.
+func WithResumeAfter(resumeToken bson.Raw) WatchOption {
+	return func(cfg *watchConfig) {
+		cfg.streamOptions.SetResumeAfter(resumeToken)
+	}
+}
+
+// WithResumeTokenStore resumes the stream after the token loaded from store, if any, and saves the token of every
+// event once the consumer is done with it, i.e. receives the next one. An event being processed when the consumer
+// stops is thus delivered again after the restart. The loaded token takes precedence over WithResumeAfter.
+func WithResumeTokenStore(store ResumeTokenStore) WatchOption {
+	return func(cfg *watchConfig) {
+		cfg.tokenStore = store
+	}
+}
+
+// WithFullDocument sets whether update events carry the current version of the document, e.g. options.UpdateLookup.
+func WithFullDocument(fullDocument options.FullDocument) WatchOption {
+	return func(cfg *watchConfig) {
+		cfg.streamOptions.SetFullDocument(fullDocument)
+	}
+}
+
+// WithFullDocumentBeforeChange sets whether events carry the document as it was before the change, e.g.
+// options.WhenAvailable. The collection must have pre-images enabled, see CreateCollectionWithPreImages.
+func WithFullDocumentBeforeChange(fullDocument options.FullDocument) WatchOption {
+	return func(cfg *watchConfig) {
+		cfg.streamOptions.SetFullDocumentBeforeChange(fullDocument)
+	}
+}
+
+// Watch delivers the changes of documents of the collection, optionally filtered by an aggregation pipeline such as
+// mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}. Both channels are closed and the
+// change stream is released when ctx is done or the stream fails, in which case the error is sent first.
+// A stream failing with a resumable error is re-established from its last resume token, or from when it was first
+// opened when it has none yet.
+// Change streams require a replica set or a sharded cluster.
+func (s *Storage) Watch(ctx context.Context, collection string, pipeline interface{}, opts ...WatchOption) (<-chan ChangeEvent, <-chan error) {
+	events := make(chan ChangeEvent)
+	errs := make(chan error, 1)
+
+	cfg := watchConfig{streamOptions: options.ChangeStream()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	go func() {
+		defer close(errs)
+		defer close(events)
+
+		if err := s.watch(ctx, collection, pipeline, cfg, events); err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+
+	return events, errs
+}
+
+// watch delivers change events into events until ctx is done or the stream fails with an error it can't resume from.
+func (s *Storage) watch(ctx context.Context, collection string, pipeline interface{}, cfg watchConfig, events chan<- ChangeEvent) error {
+	if cfg.tokenStore != nil {
+		resumeToken, err := cfg.tokenStore.Load(ctx)
+		if err != nil {
+			return err
+		}
+		if len(resumeToken) > 0 {
+			cfg.streamOptions.SetResumeAfter(resumeToken)
+		}
+	}
+
+	// an approximation of the stream start, to resume from before any event was received
+	startedAt := primitive.Timestamp{T: uint32(time.Now().Unix())}
+	idleResumes := 0
+	var processed, lastResumeToken bson.Raw
+	for {
+		stream, err := s.database.Collection(collection).Watch(ctx, pipeline, cfg.streamOptions)
+		if err != nil {
+			return err
+		}
+
+		delivered, err := deliverEvents(ctx, stream, events, cfg.tokenStore, &processed)
+		resumeToken := stream.ResumeToken()
+		// ctx may already be done, which would prevent killing the cursor on the server
+		_ = stream.Close(context.Background())
+
+		if err == nil || ctx.Err() != nil || !isResumableStreamError(err) {
+			return err
+		}
+
+		// an idle stream is resumed too, but a persistent failure isn't retried forever; the resume token of an idle
+		// stream still advances as long as the stream works
+		if delivered > 0 || !bytes.Equal(resumeToken, lastResumeToken) {
+			idleResumes = 0
+		} else if idleResumes++; idleResumes > maxIdleStreamResumes {
+			return err
+		}
+		lastResumeToken = resumeToken
+
+		// otherwise the stream is reopened from the same position
+		switch {
+		case resumeToken != nil:
+			cfg.streamOptions.StartAtOperationTime = nil
+			cfg.streamOptions.SetResumeAfter(resumeToken)
+		case cfg.streamOptions.ResumeAfter == nil && cfg.streamOptions.StartAtOperationTime == nil:
+			cfg.streamOptions.SetStartAtOperationTime(&startedAt)
+		}
+	}
+}
+
+// maxIdleStreamResumes is how many times in a row watch resumes a stream failing without making any progress.
+const maxIdleStreamResumes = 3
+
+// deliverEvents sends the events of the stream into events, saving the token of the previous event to store once
+// the next one is received. It returns the number of delivered events.
+func deliverEvents(ctx context.Context, stream *mongo.ChangeStream, events chan<- ChangeEvent, store ResumeTokenStore, processed *bson.Raw) (int, error) {
+	delivered := 0
+	for stream.Next(ctx) {
+		var event ChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			return delivered, err
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return delivered, ctx.Err()
+		}
+		delivered++
+
+		if store != nil && *processed != nil {
+			if err := store.Save(ctx, *processed); err != nil {
+				return delivered, err
+			}
+		}
+		*processed = event.ResumeToken
+	}
+
+	return delivered, stream.Err()
+}
+
+// isResumableStreamError reports whether a change stream failing with err can be resumed from its resume token.
+func isResumableStreamError(err error) bool {
+	return hasErrorLabel(err, "ResumableChangeStreamError") || mongo.IsNetworkError(err)
+}